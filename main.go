@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/anz-bank/sysl-catalog/pkg/catalog"
+	"github.com/anz-bank/sysl-catalog/pkg/config"
+	"github.com/anz-bank/sysl-catalog/pkg/parsecache"
 	"github.com/anz-bank/sysl-catalog/pkg/watcher"
 
 	"github.com/anz-bank/sysl/pkg/mod"
@@ -23,28 +27,46 @@ import (
 
 var (
 	runCmd            = kingpin.Command("run", "Run the generator")
-	input             = runCmd.Arg("input", "Input sysl file to generate documentation for").Required().String()
+	inputs            = runCmd.Arg("input", "Input sysl files, directories or globs to generate documentation for").Strings()
+	inputFormat       = runCmd.Flag("input-format", "Format of the input: a sysl file or a compiled sysl module (pb, textpb or json)").HintOptions("auto", "sysl", "pb", "textpb", "json").Default("auto").String()
+	configFile        = runCmd.Flag("config", "Project configuration file, defaults to the nearest "+config.FileName+" above the input").String()
 	plantUMLoption    = runCmd.Flag("plantuml", "Plantuml service to use").String()
+	mermaidService    = runCmd.Flag("mermaid-service", "mermaid.ink service that renders the mermaid diagrams of confluence pages, or '' to keep their source").Default(catalog.DefaultMermaidService).String()
+	baseURL           = runCmd.Flag("base-url", "URL the output is published at, used for the links in plantuml diagram SVGs").String()
+	sourceURL         = runCmd.Flag("source-url", "Template of links to source files, eg. https://github.com/org/repo/blob/master/{{.Path}}#L{{.Line}}").String()
 	port              = runCmd.Flag("port", "Port to serve on").Short('p').Default(":6900").String()
-	outputType        = runCmd.Flag("type", "Type of output").HintOptions("html", "markdown").Default("markdown").String()
+	outputType        = runCmd.Flag("type", "Type of output").HintOptions("html", "markdown", "document", "confluence").Default("markdown").String()
 	outputDir         = runCmd.Flag("output", "OutputDir directory to generate to").Short('o').String()
 	verbose           = runCmd.Flag("verbose", "Verbose logs").Short('v').Bool()
+	exports           = runCmd.Flag("export", "Also export the architecture, separated by a comma: 'dot' and/or 'structurizr'").String()
 	templates         = runCmd.Flag("templates", "Custom templates to use, separated by a comma, or 'mermaid' or 'plantuml' for defaults").String()
 	outputFileName    = runCmd.Flag("outputFileName", "Output file name for pages; {{.Title}}").Default("").String()
 	server            = runCmd.Flag("serve", "Start a http server and preview documentation").Bool()
 	noCSS             = runCmd.Flag("noCSS", "Disable adding css to served html").Bool()
 	disableLiveReload = runCmd.Flag("disableLiveReload", "Disable live reload").Default("false").Bool()
+	noCache           = runCmd.Flag("no-cache", "Parse the sysl files even if they haven't changed since they were last parsed").Bool()
+	lazy              = runCmd.Flag("lazy", "Render pages when they're first requested in server mode").Bool()
+	debounce          = runCmd.Flag("debounce", "How long to wait for more changes before regenerating in server mode").Default("300ms").Duration()
+	checkLinks        = runCmd.Flag("check-links", "Check that the links and anchors in the generated output resolve").Bool()
+	statsCharts       = runCmd.Flag("stats-charts", "Add mermaid charts to the statistics page").Bool()
+	jsonSchema        = runCmd.Flag("json-schema", "Write JSON Schemas of the types of each app: 'app', or 'type' to also write one per type").Enum("", catalog.JSONSchemaApps, catalog.JSONSchemaTypes)
+	codeOwners        = runCmd.Flag("codeowners", "Write a CODEOWNERS mapping of the sysl files to the owners of their apps").Bool()
+	checkLinksCmd     = kingpin.Command("check-links", "Check that the links and anchors in generated output resolve")
+	checkLinksDir     = checkLinksCmd.Arg("dir", "Directory of generated markdown or html").Required().String()
 	modCmd            = kingpin.Command("mod", "sysl modules")
 	cmd               = modCmd.Arg("cmd", "get or update").String()
 	repo              = modCmd.Arg("repo", "repo to get").String()
 )
 
 func main() {
-	kingpin.Parse()
+	command := kingpin.Parse()
 
 	logger := setupLogger()
-	plantUMLService := plantUMLService()
 	fs := afero.NewOsFs()
+	if command == checkLinksCmd.FullCommand() {
+		reportBrokenLinks(fs, *checkLinksDir, logger)
+		return
+	}
 	retr, err := mod.Retriever(afero.NewOsFs())
 	if err != nil {
 		logger.Fatal(err)
@@ -55,18 +77,49 @@ func main() {
 		}
 		return
 	}
+	conf, err := loadConfig(fs)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	files, err := expandInputs(fs, *inputs)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(files) == 0 {
+		logger.Fatal("no input: pass a sysl file or declare inputs in " + config.FileName)
+	}
+	title := projectTitle(files)
+	plantUMLService := plantUMLService()
 	if !*server {
-		m, err := parseSyslFile(".", *input, fs, logger)
+		m, compiled, err := parseSyslFiles(files, fs, logger)
 		if err != nil {
 			logger.Fatal(err)
 		}
 
-		catalog.NewProject(*input, plantUMLService, *outputType, logger, m, fs, *outputDir).
+		project := catalog.NewProject(title, plantUMLService, *outputType, logger, m, fs, *outputDir).
 			SetOptions(*noCSS, *outputFileName, "/").
+			WithConfig(conf.FilterRegexps, conf.MetadataKeys).
+			WithBaseURL(*baseURL).
+			WithMermaidService(*mermaidService).
+			WithSourceURLs(sourceURLs(conf)).
+			WithTheme(conf.Theme).
+			WithStatsCharts(*statsCharts).
+			WithOwnerKeys(conf.OwnerKeys...).
+			WithCodeOwners(*codeOwners).
+			WithJSONSchema(*jsonSchema).
+			WithSourceFs(fs).
+			WithSourceFiles(files...).
+			WithSourceFormats(inputFormats(files)).
+			WithCompiledApps(compiled).
 			WithRetriever(retr).
-			AutomaticTemplates(fs, strings.Split(*templates, ",")...).
-			Run()
-
+			AutomaticTemplates(fs, strings.Split(*templates, ",")...)
+		project.Run()
+		if err := project.Export(strings.Split(*exports, ",")...); err != nil {
+			logger.Fatal(err)
+		}
+		if *checkLinks && *outputDir != "" {
+			reportBrokenLinks(fs, *outputDir, logger)
+		}
 		return
 	}
 
@@ -77,44 +130,90 @@ func main() {
 		logger.Warn("OutputDir is ignored in server mode")
 	}
 
-	handler := catalog.NewProject(*input, plantUMLService, "html", logger, nil, nil, "").
+	handler := catalog.NewProject(title, plantUMLService, "html", logger, nil, nil, "").
 		SetOptions(*noCSS, *outputFileName, "").
+		WithConfig(conf.FilterRegexps, conf.MetadataKeys).
+		WithBaseURL(serverBaseURL()).
+		WithSourceURLs(sourceURLs(conf)).
+		WithTheme(conf.Theme).
+		WithStatsCharts(*statsCharts).
+		WithOwnerKeys(conf.OwnerKeys...).
+		WithCodeOwners(*codeOwners).
+		WithJSONSchema(*jsonSchema).
+		WithSourceFs(fs).
+		WithSourceFiles(files...).
+		WithSourceFormats(inputFormats(files)).
 		WithRetriever(retr).
 		AutomaticTemplates(fs, strings.Split(*templates, ",")...).
-		ServerSettings(*noCSS, !*disableLiveReload, true)
-
-	go watcher.WatchFile(func(i interface{}) {
-		logger.Info("Regenerating...")
-		m, err := func() (m *sysl.Module, err error) {
-			defer func() {
-				if r := recover(); r != nil {
-					m = nil
-					err = fmt.Errorf("%s", r)
-				}
-			}()
-			if handler.RootModule == nil {
-				m, err = parseSyslFile(".", *input, fs, logger)
-				if err != nil {
-					return nil, err
+		ServerSettings(*noCSS, !*disableLiveReload, true).
+		WithLazyRendering(*lazy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := watcher.New(watcher.Options{
+		Debounce:   *debounce,
+		Ignore:     append(append([]string{}, watcher.DefaultIgnore...), conf.Server.WatchIgnore...),
+		Extensions: inputExtensions(files),
+		OnError:    func(err error) { logger.Error(err) },
+	})
+	if err := w.Add(inputDirs(files)...); err != nil {
+		logger.Fatal(err)
+	}
+	go func() {
+		// The module being served, which is only changed by publishing another one with Update, and its apps
+		// that were loaded from compiled modules.
+		var served *sysl.Module
+		var compiled map[string]bool
+		err := w.Run(ctx, func(events []watch.Event) {
+			logger.Info("Regenerating...")
+			m, c, err := func() (m *sysl.Module, c map[string]bool, err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						m = nil
+						err = fmt.Errorf("%s", r)
+					}
+				}()
+				if served == nil || len(events) == 0 {
+					return parseSyslFiles(files, fs, logger)
 				}
-			} else {
-				var changedModule *sysl.Module
 				wd, _ := os.Getwd()
-				relativeChangedFilePath := "." + strings.TrimPrefix(i.(watch.Event).Path, wd)
-				changedModule, err = parseSyslFile(".", relativeChangedFilePath, fs, logger)
-				if err == nil {
-					m = overwriteSyslModules(handler.RootModule, changedModule)
-				} else {
-					return nil, err
+				m = served
+				c = make(map[string]bool, len(compiled))
+				for name := range compiled {
+					c[name] = true
 				}
+				for _, changed := range changedFiles(events) {
+					relativeChangedFilePath := "." + strings.TrimPrefix(changed, wd)
+					changedModule, err := parseSyslFile(".", relativeChangedFilePath, fs, logger)
+					if err != nil {
+						return nil, nil, err
+					}
+					m = overwriteSyslModules(m, changedModule)
+					addCompiledApps(c, relativeChangedFilePath, changedModule)
+				}
+				return m, c, nil
+			}()
+			if err == nil {
+				handler.WithCompiledApps(c)
 			}
-			return
-		}()
-		handler.Update(m, err)
-		livereload.ForceRefresh()
-		logger.Info(i)
-		logger.Info("Done Regenerating")
-	}, path.Dir(*input))
+			handler.Update(m, err)
+			if err == nil {
+				served, compiled = m, c
+				// Imports may be outside of the directories of the inputs.
+				if err := w.Add(localSourceFiles(m)...); err != nil {
+					logger.Error(err)
+				}
+			}
+			livereload.ForceRefresh()
+			for _, event := range events {
+				logger.Info(event)
+			}
+			logger.Info("Done Regenerating")
+		})
+		if err != nil {
+			logger.Error(err)
+		}
+	}()
 
 	http.Handle("/", handler)
 	livereload.Initialize()
@@ -124,13 +223,130 @@ func main() {
 	logger.Fatal(http.ListenAndServe(*port, nil))
 }
 
-// overwriteSyslModules takes two sysl modules and overwrites one with the other
+// serverBaseURL returns the url the server is browsed at, so the links in PlantUML diagrams lead back to it.
+func serverBaseURL() string {
+	if *baseURL != "" {
+		return *baseURL
+	}
+	if strings.HasPrefix(*port, ":") {
+		return "http://localhost" + *port
+	}
+	return "http://" + *port
+}
+
+// reportBrokenLinks prints the links in dir that don't resolve and exits with an error if there are any.
+func reportBrokenLinks(fs afero.Fs, dir string, logger *logrus.Logger) {
+	broken, err := catalog.CheckLinks(fs, dir)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	for _, link := range broken {
+		fmt.Println(link)
+	}
+	if len(broken) > 0 {
+		logger.Fatalf("%d broken links in %s", len(broken), dir)
+	}
+}
+
+// overwriteSyslModules returns a copy of existing with the apps of overwrite in place of its own, leaving
+// existing as it is (it may be being served).
 // TODO: Handle app definitions from multiple files
 func overwriteSyslModules(existing *sysl.Module, overwrite *sysl.Module) *sysl.Module {
-	for k, v := range overwrite.Apps {
-		existing.Apps[k] = v
+	merged := &sysl.Module{
+		Apps:           make(map[string]*sysl.Application, len(existing.GetApps())+len(overwrite.GetApps())),
+		SourceContexts: existing.GetSourceContexts(),
+	}
+	for k, v := range existing.GetApps() {
+		merged.Apps[k] = v
+	}
+	for k, v := range overwrite.GetApps() {
+		merged.Apps[k] = v
+	}
+	return merged
+}
+
+// loadConfig loads the project configuration file (given by --config or found above the input) and
+// copies its values into any flags that weren't set on the command line.
+func loadConfig(fs afero.Fs) (*config.Config, error) {
+	filename := *configFile
+	if filename == "" {
+		dir := "."
+		if len(*inputs) > 0 {
+			dir = path.Dir((*inputs)[0])
+		}
+		found, err := config.Find(fs, dir)
+		if err != nil {
+			return nil, err
+		}
+		if found == "" {
+			return &config.Config{}, nil
+		}
+		filename = found
+	}
+	conf, err := config.Load(fs, filename)
+	if err != nil {
+		return nil, err
+	}
+	set := flagsSetByUser()
+	setString := func(name string, flag *string, value string) {
+		if value != "" && !set[name] {
+			*flag = value
+		}
+	}
+	setBool := func(name string, flag *bool, value bool) {
+		if value && !set[name] {
+			*flag = value
+		}
 	}
-	return existing
+	if len(*inputs) == 0 {
+		*inputs = conf.Inputs
+	}
+	setString("input-format", inputFormat, conf.InputFormat)
+	setString("output", outputDir, conf.Output)
+	setString("type", outputType, conf.Type)
+	setString("templates", templates, strings.Join(conf.Templates, ","))
+	setString("export", exports, strings.Join(conf.Exports, ","))
+	setString("outputFileName", outputFileName, conf.OutputFileName)
+	setString("plantuml", plantUMLoption, conf.PlantUML)
+	setString("base-url", baseURL, conf.BaseURL)
+	setString("mermaid-service", mermaidService, conf.MermaidService)
+	setString("json-schema", jsonSchema, conf.JSONSchema)
+	setString("port", port, conf.Server.Port)
+	setBool("noCSS", noCSS, conf.NoCSS)
+	setBool("check-links", checkLinks, conf.CheckLinks)
+	setBool("stats-charts", statsCharts, conf.StatsCharts)
+	setBool("codeowners", codeOwners, conf.CodeOwners)
+	setBool("disableLiveReload", disableLiveReload, conf.Server.DisableLiveReload)
+	setBool("lazy", lazy, conf.Server.Lazy)
+	return conf, nil
+}
+
+// sourceURLs returns the source url templates of the configuration, with --source-url as the template
+// for every file that no other template is configured for.
+func sourceURLs(conf *config.Config) map[string]string {
+	templates := make(map[string]string, len(conf.SourceURLs)+1)
+	for prefix, template := range conf.SourceURLs {
+		templates[prefix] = template
+	}
+	if *sourceURL != "" {
+		templates[""] = *sourceURL
+	}
+	return templates
+}
+
+// flagsSetByUser returns the names of the flags that were passed on the command line.
+func flagsSetByUser() map[string]bool {
+	set := make(map[string]bool)
+	ctx, err := kingpin.CommandLine.ParseContext(os.Args[1:])
+	if err != nil {
+		return set
+	}
+	for _, e := range ctx.Elements {
+		if flag, ok := e.Clause.(*kingpin.FlagClause); ok {
+			set[flag.Model().Name] = true
+		}
+	}
+	return set
 }
 
 func plantUMLService() string {
@@ -152,18 +368,232 @@ func setupLogger() *logrus.Logger {
 	return logger
 }
 
+// expandInputs expands the directories (to every .sysl file beneath them) and globs in inputs into a
+// sorted list of sysl files.
+func expandInputs(fs afero.Fs, inputs []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(f string) {
+		if f = path.Clean(f); !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+	for _, in := range inputs {
+		matches := []string{in}
+		if strings.ContainsAny(in, "*?[") {
+			globbed, err := afero.Glob(fs, in)
+			if err != nil {
+				return nil, err
+			}
+			if len(globbed) == 0 {
+				return nil, fmt.Errorf("no files match %s", in)
+			}
+			matches = globbed
+		}
+		for _, match := range matches {
+			info, err := fs.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(match)
+				continue
+			}
+			err = afero.Walk(fs, match, func(f string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() && path.Ext(f) == ".sysl" {
+					add(f)
+				}
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// projectTitle returns the input if there is only one, otherwise the directory containing all of them.
+func projectTitle(files []string) string {
+	if len(files) == 1 {
+		return files[0]
+	}
+	dir := path.Dir(files[0])
+	for _, f := range files[1:] {
+		for dir != "." && dir != "/" && !strings.HasPrefix(f, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	if dir == "." || dir == "/" {
+		if wd, err := os.Getwd(); err == nil {
+			return path.Base(wd)
+		}
+	}
+	return dir
+}
+
+// inputDirs returns the directories of files that need to be watched in server mode.
+func inputDirs(files []string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, f := range files {
+		if dir := path.Dir(f); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// inputExtensions returns the extensions of the files whose changes are regenerated in server mode: sysl files
+// and files like the inputs, so documentation or generated files next to them are skipped.
+func inputExtensions(files []string) []string {
+	seen := map[string]bool{".sysl": true}
+	extensions := []string{".sysl"}
+	for _, f := range files {
+		if ext := strings.ToLower(path.Ext(f)); ext != "" && !seen[ext] {
+			seen[ext] = true
+			extensions = append(extensions, ext)
+		}
+	}
+	return extensions
+}
+
+// changedFiles returns the distinct files of events, in the order they changed.
+func changedFiles(events []watch.Event) []string {
+	seen := make(map[string]bool)
+	var changed []string
+	for _, event := range events {
+		if !seen[event.Path] {
+			seen[event.Path] = true
+			changed = append(changed, event.Path)
+		}
+	}
+	return changed
+}
+
+// localSourceFiles returns the sysl files on disk that the apps of m are defined in, which excludes
+// files retrieved from other repositories.
+func localSourceFiles(m *sysl.Module) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, appName := range catalog.SortedKeys(m.GetApps()) {
+		source := m.GetApps()[appName].GetSourceContext()
+		if file := source.GetFile(); file != "" && source.GetVersion() == "" && !seen[file] {
+			seen[file] = true
+			if _, err := os.Stat(file); err == nil {
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+// resolveInputFormat returns the format filename is loaded in: --input-format, or one guessed from its extension.
+func resolveInputFormat(filename string) string {
+	if *inputFormat == "" || *inputFormat == "auto" {
+		return catalog.InputFormat(filename)
+	}
+	return *inputFormat
+}
+
+// inputFormats returns the format each of files is loaded in, keyed by file.
+func inputFormats(files []string) map[string]string {
+	formats := make(map[string]string, len(files))
+	for _, f := range files {
+		formats[f] = resolveInputFormat(f)
+	}
+	return formats
+}
+
+// parseSyslFiles parses every root sysl file and merges them into one module, and returns the names of its apps
+// that were loaded from compiled modules.
+func parseSyslFiles(files []string, fs afero.Fs, logger *logrus.Logger) (*sysl.Module, map[string]bool, error) {
+	var modules []*sysl.Module
+	compiled := make(map[string]bool)
+	for _, f := range files {
+		m, err := parseSyslFile(".", f, fs, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		modules = append(modules, m)
+		addCompiledApps(compiled, f, m)
+	}
+	if len(modules) == 1 {
+		return modules[0], compiled, nil
+	}
+	m, err := catalog.MergeModules(modules...)
+	return m, compiled, err
+}
+
+// addCompiledApps adds the apps of m to compiled if it was loaded from a compiled module.
+func addCompiledApps(compiled map[string]bool, filename string, m *sysl.Module) {
+	if resolveInputFormat(filename) == catalog.FormatSysl {
+		return
+	}
+	for name := range m.GetApps() {
+		compiled[name] = true
+	}
+}
+
 func parseSyslFile(root string, filename string, fs afero.Fs, logger *logrus.Logger) (*sysl.Module, error) {
+	format := resolveInputFormat(filename)
+	if format != catalog.FormatSysl {
+		if !path.IsAbs(filename) {
+			filename = path.Join(root, filename)
+		}
+		return loadCompiledModule(filename, format, fs, logger)
+	}
+	cache := parseCache(fs, logger)
+	if cache != nil {
+		if m, ok := cache.Get(path.Join(root, filename)); ok {
+			logger.Infof("Loaded %s from the parse cache", filename)
+			return m, nil
+		}
+	}
 	logger.Info("Parsing...")
 	start := time.Now()
 	retr, err := mod.Retriever(fs)
 	if err != nil {
 		return nil, err
 	}
-	m, err := parse.NewParser().Parse(path.Join(root, filename), retr)
+	recorder := parsecache.NewRecorder(retr)
+	m, err := parse.NewParser().Parse(path.Join(root, filename), recorder)
 	if err != nil {
 		return nil, err
 	}
 	elapsed := time.Since(start)
 	logger.Info("Done, time elapsed: ", elapsed)
+	if cache != nil {
+		if err := cache.Put(path.Join(root, filename), m, recorder.Files()); err != nil {
+			logger.Warn("Error caching the parsed module: ", err)
+		}
+	}
 	return m, err
 }
+
+// parseCache returns the cache of parsed modules, or nil if it's disabled or there's nowhere to keep it.
+func parseCache(fs afero.Fs, logger *logrus.Logger) *parsecache.Cache {
+	if *noCache {
+		return nil
+	}
+	dir, err := parsecache.DefaultDir()
+	if err != nil {
+		logger.Debug("Parse cache disabled: ", err)
+		return nil
+	}
+	return parsecache.New(fs, dir)
+}
+
+// loadCompiledModule loads a sysl module that has already been compiled, so no parsing or retrieving
+// of imports is needed.
+func loadCompiledModule(filename, format string, fs afero.Fs, logger *logrus.Logger) (*sysl.Module, error) {
+	logger.Infof("Loading %s module...", format)
+	b, err := afero.ReadFile(fs, filename)
+	if err != nil {
+		return nil, err
+	}
+	return catalog.UnmarshallModule(b, format)
+}
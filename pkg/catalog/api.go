@@ -0,0 +1,488 @@
+// api.go: read-only JSON endpoints over the loaded module in server mode, for tools that query the catalog
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+const apiPrefix = "/api/" // The path the JSON endpoints are served under
+
+// APIProject is a project (a ~project endpoint, or the whole module) and its packages.
+type APIProject struct {
+	Name     string          `json:"name"`
+	Link     string          `json:"link"`
+	Packages []APIPackageRef `json:"packages"`
+}
+
+// APIPackageRef refers to a package from other responses.
+type APIPackageRef struct {
+	Name    string `json:"name"`
+	Project string `json:"project,omitempty"`
+	URL     string `json:"url"`
+	Link    string `json:"link"`
+}
+
+// APIPackage is a package and its apps.
+type APIPackage struct {
+	Name    string      `json:"name"`
+	Project string      `json:"project,omitempty"`
+	Link    string      `json:"link"`
+	Apps    []APIAppRef `json:"apps"`
+}
+
+// APIAppRef refers to an app from other responses.
+type APIAppRef struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+	URL         string `json:"url"`
+	Link        string `json:"link,omitempty"`
+}
+
+// APIApp is an app with its endpoints, types and dependencies.
+type APIApp struct {
+	Name        string            `json:"name"`
+	Package     string            `json:"package,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Deprecated  bool              `json:"deprecated,omitempty"`
+	Patterns    []string          `json:"patterns,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Source      APISource         `json:"source"`
+	Link        string            `json:"link,omitempty"`
+	Endpoints   []APIEndpointRef  `json:"endpoints"`
+	Types       []APITypeRef      `json:"types"`
+	Calls       []string          `json:"calls"`    // Apps this app calls
+	CalledBy    []string          `json:"calledBy"` // Apps that call this app
+}
+
+// APISource is where an app, endpoint or type is defined.
+type APISource struct {
+	File    string `json:"file,omitempty"`
+	Version string `json:"version,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// APIEndpointRef refers to an endpoint from other responses.
+type APIEndpointRef struct {
+	Name       string `json:"name"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+	URL        string `json:"url"`
+	Link       string `json:"link,omitempty"`
+}
+
+// APIEndpoint is an endpoint with its parameters, returns and calls.
+type APIEndpoint struct {
+	App         string            `json:"app"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Deprecated  bool              `json:"deprecated,omitempty"`
+	Patterns    []string          `json:"patterns,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Source      APISource         `json:"source"`
+	Link        string            `json:"link,omitempty"`
+	Params      []APIParam        `json:"params"`
+	Returns     []APIReturn       `json:"returns"`
+	Calls       []string          `json:"calls"` // "App.Endpoint" of each call, in the order they're made
+}
+
+// APIParam is a parameter of an endpoint.
+type APIParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	In   string `json:"in,omitempty"` // "path", "query", "header" or "body", if known
+	URL  string `json:"url,omitempty"`
+}
+
+// APIReturn is a return statement of an endpoint.
+type APIReturn struct {
+	Payload string `json:"payload"`
+	Type    string `json:"type,omitempty"` // "App.Type" if a type is returned
+	URL     string `json:"url,omitempty"`
+}
+
+// APITypeRef refers to a type from other responses.
+type APITypeRef struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Link string `json:"link,omitempty"`
+}
+
+// APIType is a type with its JSON Schema and where it's used.
+type APIType struct {
+	App         string                 `json:"app"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Deprecated  bool                   `json:"deprecated,omitempty"`
+	Source      APISource              `json:"source"`
+	Link        string                 `json:"link,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"` // References to other types are their API urls
+	UsedBy      []APITypeUse           `json:"usedBy"`
+}
+
+// APITypeUse is a use of a type by an endpoint or another type.
+type APITypeUse struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Link string `json:"link,omitempty"`
+}
+
+// apiError is the body of the responses of failed requests.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// apiURL returns the url of a resource of the API, from its path elements.
+func apiURL(elems ...string) string {
+	return (&url.URL{Path: apiPrefix + strings.Join(elems, "/")}).String()
+}
+
+// apiLink returns the link to anchor on a page (relative to OutputDir) of the rendered documentation, from the
+// root of the server.
+func apiLink(page, anchor string) string {
+	if page == "" {
+		return ""
+	}
+	return (&url.URL{Path: path.Join("/", page), Fragment: anchor}).String()
+}
+
+// apiSource returns where something is defined.
+func apiSource(a SourceCoder) APISource {
+	ctx := a.GetSourceContext()
+	return APISource{File: ctx.GetFile(), Version: ctx.GetVersion(), Line: sourceLineNumber(ctx)}
+}
+
+// apiAttributes returns the string attributes of a, other than the ones the catalog adds.
+func apiAttributes(a Attr) map[string]string {
+	attrs := make(map[string]string)
+	for name, attr := range a.GetAttrs() {
+		if name == macropackage_name || name == "patterns" || attr.GetS() == "" {
+			continue
+		}
+		attrs[name] = attr.GetS()
+	}
+	return attrs
+}
+
+// apiPatterns returns the patterns (~pattern) of a.
+func apiPatterns(a Attr) []string {
+	var patterns []string
+	for _, pattern := range a.GetAttrs()["patterns"].GetA().GetElt() {
+		patterns = append(patterns, pattern.GetS())
+	}
+	return patterns
+}
+
+// apiProjects returns the projects of the root module and their packages, and the packages keyed by their
+// directory ("project/package", or "package" without projects), as a package can be in several projects.
+func (p *Generator) apiProjects() ([]APIProject, map[string]APIPackage) {
+	macroPackages := map[string]*sysl.Module{"": p.RootModule}
+	if p.StartTemplateIndex == 0 {
+		macroPackages = p.ModuleAsMacroPackage(p.RootModule)
+	}
+	pages := p.appPages()
+	var projects []APIProject
+	packages := make(map[string]APIPackage)
+	for _, macroPackageName := range SortedKeys(macroPackages) {
+		project := APIProject{Name: macroPackageName}
+		if macroPackageName == "" {
+			project.Name = path.Base(p.ProjectTitle)
+			project.Link = apiLink(markdownName(p.OutputFileName, project.Name), "")
+		} else {
+			project.Link = apiLink(path.Join(macroPackageName, markdownName(p.OutputFileName, macroPackageName)), "")
+		}
+		pkgs := p.ModuleAsPackages(macroPackages[macroPackageName])
+		for _, packageName := range SortedKeys(pkgs) {
+			dir := path.Join(macroPackageName, packageName)
+			page := path.Join(dir, markdownName(p.OutputFileName, packageName))
+			pkg := APIPackage{Name: packageName, Project: macroPackageName, Link: apiLink(page, "")}
+			for _, appName := range SortedKeys(pkgs[packageName].GetApps()) {
+				pkg.Apps = append(pkg.Apps, p.apiAppRef(pages, appName))
+			}
+			packages[dir] = pkg
+			project.Packages = append(project.Packages, APIPackageRef{
+				Name: packageName, Project: macroPackageName, URL: apiURL("packages", dir), Link: pkg.Link})
+		}
+		projects = append(projects, project)
+	}
+	return projects, packages
+}
+
+// apiAppRef refers to an app of the root module.
+func (p *Generator) apiAppRef(pages map[string]string, appName string) APIAppRef {
+	app := p.RootModule.GetApps()[appName]
+	ref := APIAppRef{
+		Name:        appName,
+		Description: Attribute(app, "description"),
+		Deprecated:  Deprecated(app),
+		URL:         apiURL("apps", appName),
+	}
+	if page, ok := pages[appName]; ok {
+		ref.Link = apiLink(page, SanitiseOutputName(appName))
+		if syslutil.HasPattern(app.GetAttrs(), "db") {
+			ref.Link = apiLink(page, "Database-"+SanitiseOutputName(appName))
+		}
+	}
+	return ref
+}
+
+// APIApp returns an app of the root module, or false if there isn't one or it's ignored (~ignore).
+func (p *Generator) APIApp(appName string) (APIApp, bool) {
+	app, ok := p.RootModule.GetApps()[appName]
+	if !ok || syslutil.HasPattern(app.GetAttrs(), "ignore") {
+		return APIApp{}, false
+	}
+	pages := p.appPages()
+	ref := p.apiAppRef(pages, appName)
+	a := APIApp{
+		Name:        appName,
+		Package:     p.filteredPackageName(app),
+		Description: ref.Description,
+		Owner:       p.OwnerOf(app),
+		Deprecated:  ref.Deprecated,
+		Patterns:    apiPatterns(app),
+		Attributes:  apiAttributes(app),
+		Source:      apiSource(app),
+		Link:        ref.Link,
+		Endpoints:   []APIEndpointRef{},
+		Types:       []APITypeRef{},
+		Calls:       []string{},
+		CalledBy:    []string{},
+	}
+	for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+		if syslutil.HasPattern(app.GetEndpoints()[endpointName].GetAttrs(), "ignore") {
+			continue
+		}
+		a.Endpoints = append(a.Endpoints, APIEndpointRef{
+			Name:       endpointName,
+			Deprecated: p.endpointDeprecated(appName, endpointName),
+			URL:        apiURL("apps", appName, "endpoints", endpointName),
+			Link:       apiLink(pages[appName], p.endpointAnchor(appName, endpointName)),
+		})
+	}
+	for _, typeName := range SortedKeys(app.GetTypes()) {
+		doc := p.TypeDocs[appName+"."+typeName]
+		a.Types = append(a.Types, APITypeRef{
+			Name: typeName,
+			URL:  apiURL("types", appName, typeName),
+			Link: apiLink(doc.Page, doc.Anchor),
+		})
+	}
+	_, deps := p.dependencies(p.RootModule)
+	for _, dep := range deps {
+		switch {
+		case dep.From == appName && dep.To != appName:
+			a.Calls = append(a.Calls, dep.To)
+		case dep.To == appName && dep.From != appName:
+			a.CalledBy = append(a.CalledBy, dep.From)
+		}
+	}
+	sort.Strings(a.CalledBy)
+	return a, true
+}
+
+// apiParam returns a parameter of an endpoint of app.
+func apiParam(appName, name string, t *sysl.Type, in string) APIParam {
+	param := APIParam{Name: name, Type: FieldType(t), In: in}
+	if references := typeReferences(appName, t); len(references) == 1 {
+		refAppName, typeName := splitReference(references[0])
+		param.URL = apiURL("types", refAppName, typeName)
+	}
+	return param
+}
+
+// APIEndpoint returns an endpoint of an app of the root module, or false if there isn't one or it's ignored.
+func (p *Generator) APIEndpoint(appName, endpointName string) (APIEndpoint, bool) {
+	app := p.RootModule.GetApps()[appName]
+	endpoint, ok := app.GetEndpoints()[endpointName]
+	if !ok || syslutil.HasPattern(endpoint.GetAttrs(), "ignore") || syslutil.HasPattern(app.GetAttrs(), "ignore") {
+		return APIEndpoint{}, false
+	}
+	e := APIEndpoint{
+		App:         appName,
+		Name:        endpointName,
+		Description: Attribute(endpoint, "description"),
+		Deprecated:  p.endpointDeprecated(appName, endpointName),
+		Patterns:    apiPatterns(endpoint),
+		Attributes:  apiAttributes(endpoint),
+		Source:      apiSource(endpoint),
+		Params:      []APIParam{},
+		Returns:     []APIReturn{},
+		Calls:       []string{},
+	}
+	if page, ok := p.appPages()[appName]; ok {
+		e.Link = apiLink(page, p.endpointAnchor(appName, endpointName))
+	}
+	for _, param := range endpoint.GetRestParams().GetUrlParam() {
+		e.Params = append(e.Params, apiParam(appName, param.GetName(), param.GetType(), "path"))
+	}
+	for _, param := range endpoint.GetRestParams().GetQueryParam() {
+		e.Params = append(e.Params, apiParam(appName, param.GetName(), param.GetType(), "query"))
+	}
+	for _, param := range endpoint.GetParam() {
+		var in string
+		for _, pattern := range []string{"body", "header"} {
+			if syslutil.HasPattern(param.GetType().GetAttrs(), pattern) {
+				in = pattern
+			}
+		}
+		e.Params = append(e.Params, apiParam(appName, param.GetName(), param.GetType(), in))
+	}
+	forEachStatement(endpoint.GetStmt(), func(s *sysl.Statement) {
+		if s.GetRet() == nil {
+			return
+		}
+		ret := APIReturn{Payload: s.GetRet().GetPayload(), Type: returnReference(appName, s.GetRet())}
+		if ret.Type != "" {
+			refAppName, typeName := splitReference(ret.Type)
+			ret.URL = apiURL("types", refAppName, typeName)
+		}
+		e.Returns = append(e.Returns, ret)
+	})
+	forEachCall(endpoint.GetStmt(), func(call *sysl.Call) {
+		e.Calls = append(e.Calls, JoinAppNameString(call.GetTarget())+"."+call.GetEndpoint())
+	})
+	return e, true
+}
+
+// APIType returns a type of an app of the root module, or false if there isn't one.
+func (p *Generator) APIType(appName, typeName string) (APIType, bool) {
+	t, ok := p.RootModule.GetApps()[appName].GetTypes()[typeName]
+	if !ok {
+		return APIType{}, false
+	}
+	reference := appName + "." + typeName
+	doc := p.TypeDocs[reference]
+	a := APIType{
+		App:         appName,
+		Name:        typeName,
+		Description: Attribute(t, "description"),
+		Deprecated:  Deprecated(t),
+		Source:      apiSource(t),
+		Link:        apiLink(doc.Page, doc.Anchor),
+		UsedBy:      []APITypeUse{},
+	}
+	if p.Mapper != nil {
+		if simpleType, ok := p.Mapper.SimpleTypes[reference]; ok {
+			a.Schema = p.typeSchema(simpleType, t, func(reference string) string {
+				refAppName, refTypeName := splitReference(reference)
+				return apiURL("types", refAppName, refTypeName)
+			})
+			a.Schema["$schema"] = JSONSchemaDraft
+		}
+	}
+	for _, u := range p.TypeUses[reference] {
+		a.UsedBy = append(a.UsedBy, APITypeUse{Kind: u.Kind, Name: u.Name, Link: apiLink(u.Page, u.Anchor)})
+	}
+	return a, true
+}
+
+// ServeAPI serves the JSON endpoints:
+//
+//	/api/projects                          the projects and their packages
+//	/api/packages, /api/packages/{name}    the packages and their apps, {project}/{name} in projects
+//	/api/apps, /api/apps/{name}            the apps and their endpoints, types and dependencies
+//	/api/apps/{name}/endpoints/{endpoint}  an endpoint with its params, returns and calls
+//	/api/types/{app}/{type}                a type with its JSON Schema and uses
+func (p *Generator) ServeAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		p.writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed: " + r.Method})
+		return
+	}
+	if len(p.errs) > 0 {
+		p.writeJSON(w, http.StatusInternalServerError, apiError{Error: fmt.Sprint(p.errs)})
+		return
+	}
+	if p.RootModule == nil {
+		p.writeJSON(w, http.StatusServiceUnavailable, apiError{Error: "the module hasn't been loaded yet"})
+		return
+	}
+	route := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	notFound := func(what string) {
+		p.writeJSON(w, http.StatusNotFound, apiError{Error: what + " not found"})
+	}
+	switch {
+	case strings.TrimSuffix(route, "/") == "projects":
+		projects, _ := p.apiProjects()
+		p.writeJSON(w, http.StatusOK, projects)
+	case strings.TrimSuffix(route, "/") == "packages":
+		_, packages := p.apiProjects()
+		refs := []APIPackageRef{}
+		for _, dir := range SortedKeys(packages) {
+			pkg := packages[dir]
+			refs = append(refs, APIPackageRef{Name: pkg.Name, Project: pkg.Project, URL: apiURL("packages", dir), Link: pkg.Link})
+		}
+		p.writeJSON(w, http.StatusOK, refs)
+	case strings.HasPrefix(route, "packages/"):
+		packageName := strings.TrimSuffix(strings.TrimPrefix(route, "packages/"), "/")
+		_, packages := p.apiProjects()
+		if pkg, ok := packages[packageName]; ok {
+			p.writeJSON(w, http.StatusOK, pkg)
+			return
+		}
+		notFound("package " + packageName)
+	case strings.TrimSuffix(route, "/") == "apps":
+		pages := p.appPages()
+		refs := []APIAppRef{}
+		for _, appName := range SortedKeys(p.RootModule.GetApps()) {
+			if !syslutil.HasPattern(p.RootModule.GetApps()[appName].GetAttrs(), "ignore") {
+				refs = append(refs, p.apiAppRef(pages, appName))
+			}
+		}
+		p.writeJSON(w, http.StatusOK, refs)
+	case strings.HasPrefix(route, "apps/"):
+		// Endpoint names can contain slashes (eg. "GET /orders/{id}"), so they're the rest of the path.
+		appName := strings.TrimPrefix(route, "apps/")
+		if i := strings.Index(appName, "/endpoints/"); i >= 0 {
+			endpointName := appName[i+len("/endpoints/"):]
+			appName = appName[:i]
+			if e, ok := p.APIEndpoint(appName, endpointName); ok {
+				p.writeJSON(w, http.StatusOK, e)
+				return
+			}
+			notFound("endpoint " + appName + "." + endpointName)
+			return
+		}
+		appName = strings.TrimSuffix(appName, "/")
+		if a, ok := p.APIApp(appName); ok {
+			p.writeJSON(w, http.StatusOK, a)
+			return
+		}
+		notFound("app " + appName)
+	case strings.HasPrefix(route, "types/"):
+		appName, typeName := path.Split(strings.TrimSuffix(strings.TrimPrefix(route, "types/"), "/"))
+		appName = strings.TrimSuffix(appName, "/")
+		if t, ok := p.APIType(appName, typeName); ok {
+			p.writeJSON(w, http.StatusOK, t)
+			return
+		}
+		notFound("type " + appName + "." + typeName)
+	default:
+		notFound("resource " + r.URL.Path)
+	}
+}
+
+// writeJSON writes v as the JSON body of a response with status.
+func (p *Generator) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		p.Log.Error(err)
+		status = http.StatusInternalServerError
+		b = []byte(`{"error": "` + http.StatusText(status) + `"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		p.Log.Info(err)
+	}
+}
@@ -0,0 +1,150 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiSysl = `
+Orders:
+    @package = "Orders"
+    @owner.team = "Checkout"
+    /orders/{id <: int}:
+        GET ?expand=string:
+            Customers <- GetCustomer
+            return ok <: Order
+    !type Order:
+        id <: int
+        customer <: Customers.Customer
+Customers:
+    @package = "Customers"
+    GetCustomer: ...
+    !type Customer:
+        name <: string
+`
+
+func serveAPI(t *testing.T, p *Generator, method, target string, v interface{}) int {
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	if v != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v))
+	}
+	return rec.Code
+}
+
+func TestServeAPI(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(apiSysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+	p.Run()
+
+	var projects []APIProject
+	assert.Equal(t, http.StatusOK, serveAPI(t, p, http.MethodGet, "/api/projects", &projects))
+	require.Len(t, projects, 1)
+	assert.Equal(t, "temp.sysl", projects[0].Name)
+	assert.Equal(t, []APIPackageRef{
+		{Name: "Customers", URL: "/api/packages/Customers", Link: "/Customers/README.md"},
+		{Name: "Orders", URL: "/api/packages/Orders", Link: "/Orders/README.md"},
+	}, projects[0].Packages)
+
+	var pkg APIPackage
+	assert.Equal(t, http.StatusOK, serveAPI(t, p, http.MethodGet, "/api/packages/Orders", &pkg))
+	assert.Equal(t, []APIAppRef{{Name: "Orders", URL: "/api/apps/Orders", Link: "/Orders/README.md#Orders"}}, pkg.Apps)
+
+	var app APIApp
+	assert.Equal(t, http.StatusOK, serveAPI(t, p, http.MethodGet, "/api/apps/Orders", &app))
+	assert.Equal(t, "Orders", app.Package)
+	assert.Equal(t, "Checkout", app.Owner)
+	assert.Equal(t, []string{"Customers"}, app.Calls)
+	assert.Empty(t, app.CalledBy)
+	require.Len(t, app.Endpoints, 1)
+	assert.Equal(t, "GET /orders/{id}", app.Endpoints[0].Name)
+	assert.Equal(t, "/api/apps/Orders/endpoints/GET%20/orders/%7Bid%7D", app.Endpoints[0].URL)
+	assert.Equal(t, []APITypeRef{{Name: "Order", URL: "/api/types/Orders/Order", Link: "/Orders/README.md#Orders.Order"}},
+		app.Types)
+
+	var endpoint APIEndpoint
+	assert.Equal(t, http.StatusOK, serveAPI(t, p, http.MethodGet, app.Endpoints[0].URL, &endpoint))
+	assert.Equal(t, []APIParam{
+		{Name: "id", Type: "int", In: "path"},
+		{Name: "expand", Type: "string", In: "query"},
+	}, endpoint.Params)
+	assert.Equal(t, []APIReturn{{Payload: "ok <: Order", Type: "Orders.Order", URL: "/api/types/Orders/Order"}},
+		endpoint.Returns)
+	assert.Equal(t, []string{"Customers.GetCustomer"}, endpoint.Calls)
+
+	var typ APIType
+	assert.Equal(t, http.StatusOK, serveAPI(t, p, http.MethodGet, "/api/types/Orders/Order", &typ))
+	properties := typ.Schema["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$ref": "/api/types/Customers/Customer"}, properties["customer"])
+	assert.Equal(t, []APITypeUse{
+		{Kind: "Return", Name: "Orders.GET /orders/{id}", Link: "/Orders/README.md#Orders-GETorders%7Bid%7D"},
+	}, typ.UsedBy)
+
+	var apiErr apiError
+	assert.Equal(t, http.StatusNotFound, serveAPI(t, p, http.MethodGet, "/api/apps/Missing", &apiErr))
+	assert.Equal(t, "app Missing not found", apiErr.Error)
+	assert.Equal(t, http.StatusNotFound,
+		serveAPI(t, p, http.MethodGet, "/api/types/Orders/"+url.PathEscape("Missing"), nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, serveAPI(t, p, http.MethodPost, "/api/projects", nil))
+}
+
+const apiProjectsSysl = `
+Proj[~project]:
+    Retail:
+        Retail Orders
+    Wholesale:
+        Wholesale Orders
+
+RetailOrders:
+    @package = "Retail Orders"
+    Get: ...
+
+WholesaleOrders:
+    @package = "Wholesale Orders"
+    Get: ...
+
+Hidden[~ignore]:
+    @package = "Retail Orders"
+    Get: ...
+`
+
+func TestServeAPIProjects(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(apiProjectsSysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out").
+		WithConfig([]*regexp.Regexp{regexp.MustCompile("^(Retail|Wholesale) ")}, nil)
+	p.Run()
+
+	// Packages of different projects (macro packages) can have the same name.
+	var refs []APIPackageRef
+	assert.Equal(t, http.StatusOK, serveAPI(t, p, http.MethodGet, "/api/packages", &refs))
+	assert.Equal(t, []APIPackageRef{
+		{Name: "Orders", Project: "Retail", URL: "/api/packages/Retail/Orders", Link: "/Retail/Orders/README.md"},
+		{Name: "Orders", Project: "Wholesale", URL: "/api/packages/Wholesale/Orders", Link: "/Wholesale/Orders/README.md"},
+	}, refs)
+	var pkg APIPackage
+	assert.Equal(t, http.StatusOK, serveAPI(t, p, http.MethodGet, refs[1].URL, &pkg))
+	assert.Equal(t, "Wholesale", pkg.Project)
+	assert.Equal(t, []APIAppRef{
+		{Name: "WholesaleOrders", URL: "/api/apps/WholesaleOrders", Link: "/Wholesale/Orders/README.md#WholesaleOrders"},
+	}, pkg.Apps)
+
+	assert.Equal(t, http.StatusNotFound, serveAPI(t, p, http.MethodGet, "/api/apps/Hidden", nil))
+	assert.Equal(t, http.StatusNotFound, serveAPI(t, p, http.MethodGet, "/api/apps/Hidden/endpoints/Get", nil))
+}
@@ -0,0 +1,178 @@
+// c4.go: C4 system context and container diagrams of the project structure
+package catalog
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+// c4Element is a system, container or app drawn in a C4 diagram.
+type c4Element struct {
+	ID       string
+	Kind     string // A C4 macro, eg. "System", "System_Ext" or "ContainerDb"
+	Label    string
+	Desc     string
+	Boundary string // The system a container is drawn in
+}
+
+// c4Diagram is the elements and relationships of a C4 diagram.
+type c4Diagram struct {
+	Elements   []*c4Element
+	Boundaries []string
+	Rels       []Dependency // Between element ids
+}
+
+// c4System returns the system an app is part of: its macro package, or the project when there are none.
+func (p *Generator) c4System(macroPackageOf map[string]string, appName string) string {
+	if system, ok := macroPackageOf[appName]; ok {
+		return system
+	}
+	return path.Base(p.ProjectTitle)
+}
+
+// c4Diagram returns the C4 diagram of the apps of m; container diagrams show the packages of each system
+// in m, context diagrams only the systems.
+func (p *Generator) c4Diagram(m *sysl.Module, containers bool) c4Diagram {
+	var d c4Diagram
+	if p.RootModule == nil || m == nil {
+		return d
+	}
+	macroPackageOf := make(map[string]string)
+	if p.StartTemplateIndex == 0 {
+		for macroPackageName, macroPackage := range p.ModuleAsMacroPackage(p.RootModule) {
+			for appName := range macroPackage.GetApps() {
+				macroPackageOf[appName] = macroPackageName
+			}
+		}
+	}
+	elements := make(map[string]*c4Element)
+	boundaries := make(map[string]bool)
+	elementOf := func(appName string) *c4Element {
+		app := p.RootModule.GetApps()[appName]
+		_, inM := m.GetApps()[appName]
+		external := syslutil.HasPattern(app.GetAttrs(), "external")
+		db := syslutil.HasPattern(app.GetAttrs(), "db")
+		system := p.c4System(macroPackageOf, appName)
+		var key string
+		e := &c4Element{}
+		switch {
+		case external:
+			key, e.Kind, e.Label, e.Desc = "app:"+appName, "System_Ext", appName, Attribute(app, "description")
+			if db {
+				e.Kind = "SystemDb_Ext"
+			}
+		case containers && inM && db:
+			key, e.Kind, e.Label, e.Desc, e.Boundary = "app:"+appName, "ContainerDb", appName, Attribute(app, "description"), system
+		case containers && inM:
+			pkg := p.filteredPackageName(app)
+			key, e.Kind, e.Label, e.Boundary = "container:"+system+"/"+pkg, "Container", pkg, system
+		default:
+			key, e.Kind, e.Label = "system:"+system, "System", system
+		}
+		if existing, ok := elements[key]; ok {
+			return existing
+		}
+		e.ID = fmt.Sprintf("c4_%d", len(elements))
+		elements[key] = e
+		d.Elements = append(d.Elements, e)
+		if e.Boundary != "" && !boundaries[e.Boundary] {
+			boundaries[e.Boundary] = true
+			d.Boundaries = append(d.Boundaries, e.Boundary)
+		}
+		return e
+	}
+	for _, appName := range SortedKeys(m.GetApps()) {
+		attrs := m.GetApps()[appName].GetAttrs()
+		if !syslutil.HasPattern(attrs, "ignore") && !syslutil.HasPattern(attrs, "project") {
+			elementOf(appName)
+		}
+	}
+	seen := make(map[Dependency]bool)
+	_, deps := p.dependencies(m)
+	for _, dep := range deps {
+		if _, ok := p.RootModule.GetApps()[dep.To]; !ok {
+			continue
+		}
+		rel := Dependency{From: elementOf(dep.From).ID, To: elementOf(dep.To).ID}
+		if rel.From != rel.To && !seen[rel] {
+			seen[rel] = true
+			d.Rels = append(d.Rels, rel)
+		}
+	}
+	return d
+}
+
+// c4Lines writes the elements (in their boundaries) and relationships of d with the C4 macros shared by
+// C4-PlantUML and mermaid.
+func (d c4Diagram) c4Lines(b *strings.Builder) {
+	element := func(indent string, e *c4Element) {
+		switch e.Kind {
+		case "Container", "ContainerDb":
+			fmt.Fprintf(b, "%s%s(%s, %s, \"\", %s)\n", indent, e.Kind, e.ID, quote(e.Label), quote(e.Desc))
+		default:
+			fmt.Fprintf(b, "%s%s(%s, %s, %s)\n", indent, e.Kind, e.ID, quote(e.Label), quote(e.Desc))
+		}
+	}
+	for i, boundary := range d.Boundaries {
+		fmt.Fprintf(b, "System_Boundary(b%d, %s) {\n", i, quote(boundary))
+		for _, e := range d.Elements {
+			if e.Boundary == boundary {
+				element("    ", e)
+			}
+		}
+		b.WriteString("}\n")
+	}
+	for _, e := range d.Elements {
+		if e.Boundary == "" {
+			element("", e)
+		}
+	}
+	for _, rel := range d.Rels {
+		fmt.Fprintf(b, "Rel(%s, %s, \"Uses\")\n", rel.From, rel.To)
+	}
+}
+
+// C4ContextPlantuml returns a C4-PlantUML system context diagram of the systems of m as a plantuml url.
+func (p *Generator) C4ContextPlantuml(m *sysl.Module, title string) string {
+	return p.c4Plantuml(p.c4Diagram(m, false), "C4_Context", title)
+}
+
+// C4ContainerPlantuml returns a C4-PlantUML container diagram of the packages of m as a plantuml url.
+func (p *Generator) C4ContainerPlantuml(m *sysl.Module, title string) string {
+	return p.c4Plantuml(p.c4Diagram(m, true), "C4_Container", title)
+}
+
+func (p *Generator) c4Plantuml(d c4Diagram, include, title string) string {
+	if len(d.Elements) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "@startuml\n!include <C4/%s>\ntitle %s\n", include, title)
+	d.c4Lines(&b)
+	b.WriteString("@enduml\n")
+	return p.plantumlURL(b.String())
+}
+
+// C4ContextMermaid returns a mermaid system context diagram of the systems of m.
+func (p *Generator) C4ContextMermaid(m *sysl.Module, title string) string {
+	return p.themeMermaid(c4Mermaid(p.c4Diagram(m, false), "C4Context", title))
+}
+
+// C4ContainerMermaid returns a mermaid container diagram of the packages of m.
+func (p *Generator) C4ContainerMermaid(m *sysl.Module, title string) string {
+	return p.themeMermaid(c4Mermaid(p.c4Diagram(m, true), "C4Container", title))
+}
+
+func c4Mermaid(d c4Diagram, kind, title string) string {
+	if len(d.Elements) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\ntitle %s\n", kind, title)
+	d.c4Lines(&b)
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const c4Sysl = `
+Project[~project]:
+    Shop:
+        Orders
+    Logistics:
+        Fulfilment
+
+Orders:
+    @package = "Orders"
+    PlaceOrder:
+        Shipping <- Ship
+        Bank <- Pay
+        OrdersDb <- Insert
+
+OrdersDb[~db]:
+    @package = "Orders"
+    Insert: ...
+
+Shipping:
+    @package = "Fulfilment"
+    Ship: ...
+
+Bank[~external]:
+    @package = "Fulfilment"
+    @description = "The bank"
+    Pay: ...
+`
+
+func TestC4ContextMermaid(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(c4Sysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	assert.Equal(t, `C4Context
+title test
+System_Ext(c4_0, "Bank", "The bank")
+System(c4_1, "Shop", "")
+System(c4_2, "Logistics", "")
+Rel(c4_1, c4_2, "Uses")
+Rel(c4_1, c4_0, "Uses")
+`, p.C4ContextMermaid(m, "test"))
+}
+
+func TestC4ContainerMermaid(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(c4Sysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	shop := p.ModuleAsMacroPackage(m)["Shop"]
+	assert.Equal(t, `C4Container
+title Shop
+System_Boundary(b0, "Shop") {
+    Container(c4_0, "Orders", "", "")
+    ContainerDb(c4_1, "OrdersDb", "", "")
+}
+System(c4_2, "Logistics", "")
+System_Ext(c4_3, "Bank", "The bank")
+Rel(c4_0, c4_2, "Uses")
+Rel(c4_0, c4_3, "Uses")
+Rel(c4_0, c4_1, "Uses")
+`, p.C4ContainerMermaid(shop, "Shop"))
+	assert.NotEmpty(t, p.C4ContainerPlantuml(shop, "Shop"))
+}
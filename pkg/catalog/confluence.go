@@ -0,0 +1,260 @@
+// confluence.go: converts the generated pages to Confluence storage format along with a manifest of the page tree
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// ConfluenceManifest is the file that describes the page tree for an uploader.
+const ConfluenceManifest = "confluence.json"
+
+var (
+	confluenceScript  = regexp.MustCompile(`(?s)<script[^>]*>.*?</script>`)
+	confluenceAnchor  = regexp.MustCompile(`<a name="([^"]*)"></a>`)
+	unquotedAnchor    = regexp.MustCompile(`<a name=([^"].*?)></a>`)
+	confluenceFooter  = regexp.MustCompile(`(?s)<(pre|div) class="footer">.*?(</(pre|div)>|$)`)
+	confluenceLink    = regexp.MustCompile(`(?s)<a href="([^"]*)">(.*?)</a>`)
+	confluenceDetails = regexp.MustCompile(`(?s)<details( open)?>\s*<summary>(.*?)</summary>`)
+	confluenceMermaid = regexp.MustCompile(`(?s)<pre class="mermaid">(.*?)</pre>`)
+	confluenceCode    = regexp.MustCompile(`(?s)<pre><code(?: class="language-([^"]+)")?>(.*?)</code></pre>`)
+	confluenceImage   = regexp.MustCompile(`<img src="([^"]*)"[^>]*>`)
+	htmlTag           = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ConfluencePage is a page in the Confluence page tree; File is the storage format of the page relative
+// to the output directory.
+type ConfluencePage struct {
+	Title       string                 `json:"title"`
+	File        string                 `json:"file"`
+	Attachments []ConfluenceAttachment `json:"attachments,omitempty"`
+	Children    []*ConfluencePage      `json:"children,omitempty"`
+}
+
+// ConfluenceAttachment is a diagram that has to be uploaded with a page, from URL or from Path relative to
+// the output directory.
+type ConfluenceAttachment struct {
+	FileName string `json:"fileName"`
+	URL      string `json:"url,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// DefaultMermaidService renders the mermaid diagrams of Confluence pages unless another service is set.
+const DefaultMermaidService = "https://mermaid.ink"
+
+// WithMermaidService sets the mermaid.ink service that renders the mermaid diagrams of Confluence pages to
+// SVGs, which are attached to the pages. Diagrams are kept as their source if it's "".
+func (p *Generator) WithMermaidService(mermaidService string) *Generator {
+	p.MermaidService = mermaidService
+	return p
+}
+
+// RunConfluence generates every page as markdown, converts them to Confluence storage format (dir/page.xhtml)
+// and writes the page tree to OutputDir/confluence.json.
+func (p *Generator) RunConfluence() error {
+	pages, dirs, err := p.markdownPages()
+	if err != nil {
+		return err
+	}
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(gmhtml.WithUnsafe(), gmhtml.WithXHTML()),
+	)
+	titles := make(map[string]string, len(dirs))
+	raws := make(map[string][]byte, len(dirs))
+	for _, dir := range dirs {
+		if raws[dir], titles[dir], err = readMarkdownPage(pages, dir); err != nil {
+			return err
+		}
+		// Anchors aren't quoted in the templates, so names with '>' would end the tag early, and an anchor
+		// followed by <details> on the same line would wrap the <details> in a paragraph.
+		raws[dir] = unquotedAnchor.ReplaceAllFunc(raws[dir], func(b []byte) []byte {
+			return []byte(`<a name="` + html.EscapeString(string(unquotedAnchor.FindSubmatch(b)[1])) + `"></a>`)
+		})
+		raws[dir] = bytes.ReplaceAll(raws[dir], []byte("</a><details>"), []byte("</a>\n\n<details>"))
+	}
+	uniqueTitles(titles)
+	byDir := make(map[string]*ConfluencePage, len(dirs))
+	var root *ConfluencePage
+	for _, dir := range dirs {
+		var converted bytes.Buffer
+		if err := md.Convert(raws[dir], &converted); err != nil {
+			return errors.Wrap(err, "Error converting markdown to html:")
+		}
+		page := &ConfluencePage{Title: titles[dir], File: path.Join(dir, outputFileNames["confluence"])}
+		body := p.confluenceStorage(dir, converted.String(), titles, page)
+		if err := p.Fs.MkdirAll(path.Join(p.OutputDir, dir), os.ModePerm); err != nil {
+			return err
+		}
+		if err := afero.WriteFile(p.Fs, path.Join(p.OutputDir, page.File), []byte(body), 0644); err != nil {
+			return err
+		}
+		byDir[dir] = page
+		if root == nil {
+			root = page
+			continue
+		}
+		parent := root
+		for d := dir; d != ""; {
+			if d = pageDir(d, ""); byDir[d] != nil {
+				parent = byDir[d]
+				break
+			}
+		}
+		parent.Children = append(parent.Children, page)
+	}
+	manifest, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return err
+	}
+	return afero.WriteFile(p.Fs, path.Join(p.OutputDir, ConfluenceManifest), manifest, 0644)
+}
+
+// confluenceStorage converts the html of the page in dir to Confluence storage format: anchors, links to other
+// pages, <details>, code blocks and diagrams become their Confluence macros, and images are added to the
+// attachments of page.
+func (p *Generator) confluenceStorage(dir, body string, titles map[string]string, page *ConfluencePage) string {
+	body = confluenceScript.ReplaceAllString(body, "")
+	body = confluenceFooter.ReplaceAllString(body, "")
+	body = confluenceAnchor.ReplaceAllString(body,
+		`<ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">$1</ac:parameter></ac:structured-macro>`)
+	body = confluenceLink.ReplaceAllStringFunc(body, func(s string) string {
+		m := confluenceLink.FindStringSubmatch(s)
+		href, text := html.UnescapeString(m[1]), m[2]
+		target, anchor := href, ""
+		if i := strings.Index(href, "#"); i >= 0 {
+			target, anchor = href[:i], href[i+1:]
+		}
+		var ri string
+		if target != "" {
+			title, ok := titles[pageDir(dir, target)]
+			if strings.Contains(target, ":") || path.Ext(target) != ".md" || !ok {
+				return s
+			}
+			ri = fmt.Sprintf(`<ri:page ri:content-title="%s"/>`, html.EscapeString(title))
+		}
+		var a string
+		if anchor != "" {
+			a = fmt.Sprintf(` ac:anchor="%s"`, html.EscapeString(anchor))
+		}
+		return fmt.Sprintf(`<ac:link%s>%s<ac:link-body>%s</ac:link-body></ac:link>`, a, ri, text)
+	})
+	body = confluenceDetails.ReplaceAllStringFunc(body, func(s string) string {
+		title := htmlTag.ReplaceAllString(confluenceDetails.FindStringSubmatch(s)[2], "")
+		return fmt.Sprintf(`<ac:structured-macro ac:name="expand"><ac:parameter ac:name="title">%s</ac:parameter>`+
+			`<ac:rich-text-body>`, strings.TrimSpace(title))
+	})
+	body = strings.ReplaceAll(body, "</details>", "</ac:rich-text-body></ac:structured-macro>")
+	body = confluenceMermaid.ReplaceAllStringFunc(body, func(s string) string {
+		source := confluenceMermaid.FindStringSubmatch(s)[1]
+		if p.MermaidService == "" {
+			// The code macro has no mermaid language, so the source is kept as it is.
+			return confluenceNoformatMacro(source)
+		}
+		a := ConfluenceAttachment{
+			FileName: fmt.Sprintf("diagram-%d.svg", len(page.Attachments)),
+			URL:      MermaidURL(p.MermaidService, html.UnescapeString(source)),
+		}
+		page.Attachments = append(page.Attachments, a)
+		return fmt.Sprintf(`<ac:image><ri:attachment ri:filename="%s"/></ac:image>`, a.FileName)
+	})
+	body = confluenceCode.ReplaceAllStringFunc(body, func(s string) string {
+		m := confluenceCode.FindStringSubmatch(s)
+		return confluenceCodeMacro(m[1], m[2])
+	})
+	return confluenceImage.ReplaceAllStringFunc(body, func(s string) string {
+		src := html.UnescapeString(confluenceImage.FindStringSubmatch(s)[1])
+		switch {
+		case src == "":
+			return ""
+		case p.PlantumlService != "" && strings.HasPrefix(src, p.PlantumlService):
+			a := ConfluenceAttachment{FileName: fmt.Sprintf("diagram-%d.svg", len(page.Attachments)), URL: src}
+			page.Attachments = append(page.Attachments, a)
+			return fmt.Sprintf(`<ac:image><ri:attachment ri:filename="%s"/></ac:image>`, a.FileName)
+		case strings.Contains(src, ":"):
+			return fmt.Sprintf(`<ac:image><ri:url ri:value="%s"/></ac:image>`, html.EscapeString(src))
+		default:
+			a := ConfluenceAttachment{FileName: path.Base(src), Path: path.Join(dir, src)}
+			page.Attachments = append(page.Attachments, a)
+			return fmt.Sprintf(`<ac:image><ri:attachment ri:filename="%s"/></ac:image>`, html.EscapeString(a.FileName))
+		}
+	})
+}
+
+// uniqueTitles qualifies the titles (keyed by page directory) that more than one page has with the title of their
+// parent page, or their directory, as links to Confluence pages are by title. The root page keeps its title.
+func uniqueTitles(titles map[string]string) {
+	count := func() map[string]int {
+		count := make(map[string]int, len(titles))
+		for _, title := range titles {
+			count[title]++
+		}
+		return count
+	}
+	duplicates := count()
+	original := make(map[string]string, len(titles))
+	for dir, title := range titles {
+		original[dir] = title
+	}
+	for dir, title := range original {
+		if dir == "" || duplicates[title] < 2 {
+			continue
+		}
+		for d := dir; d != ""; {
+			if d = pageDir(d, ""); original[d] != "" {
+				titles[dir] = fmt.Sprintf("%s (%s)", title, original[d])
+				break
+			}
+		}
+	}
+	// Pages with the same title under parents with the same title, too.
+	duplicates = count()
+	for dir, title := range titles {
+		if dir != "" && duplicates[title] > 1 {
+			titles[dir] = fmt.Sprintf("%s (%s)", original[dir], dir)
+		}
+	}
+}
+
+// pageDir returns the directory of the page that target links to from the page in dir, or the parent
+// directory of dir when target is empty.
+func pageDir(dir, target string) string {
+	if d := path.Dir(path.Join(dir, target)); d != "." {
+		return d
+	}
+	return ""
+}
+
+// confluenceCodeMacro returns a code macro of the (html escaped) code.
+func confluenceCodeMacro(language, code string) string {
+	var b strings.Builder
+	b.WriteString(`<ac:structured-macro ac:name="code">`)
+	if language != "" {
+		fmt.Fprintf(&b, `<ac:parameter ac:name="language">%s</ac:parameter>`, language)
+	}
+	fmt.Fprintf(&b, `<ac:plain-text-body>%s</ac:plain-text-body></ac:structured-macro>`, confluenceCDATA(code))
+	return b.String()
+}
+
+// confluenceNoformatMacro returns a noformat macro of the (html escaped) text.
+func confluenceNoformatMacro(text string) string {
+	return `<ac:structured-macro ac:name="noformat"><ac:plain-text-body>` + confluenceCDATA(text) +
+		`</ac:plain-text-body></ac:structured-macro>`
+}
+
+// confluenceCDATA returns a CDATA section of the (html escaped) text.
+func confluenceCDATA(text string) string {
+	return "<![CDATA[" + strings.ReplaceAll(html.UnescapeString(text), "]]>", "]]]]><![CDATA[>") + "]]>"
+}
@@ -0,0 +1,140 @@
+package catalog
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validStorageFormat returns an error when page isn't well formed XHTML with Confluence's namespaces.
+func validStorageFormat(page string) error {
+	d := xml.NewDecoder(strings.NewReader(
+		`<root xmlns:ac="http://atlassian.com/content" xmlns:ri="http://atlassian.com/resource/identifier">` +
+			page + `</root>`))
+	d.Entity = xml.HTMLEntity
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func TestRunConfluence(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("test", plantumlService, "confluence", logrus.New(), m, fs, "out").
+		WithMermaidService(DefaultMermaidService)
+	p.Run()
+
+	b, err := afero.ReadFile(fs, "out/"+ConfluenceManifest)
+	require.NoError(t, err)
+	var root ConfluencePage
+	require.NoError(t, json.Unmarshal(b, &root))
+	assert.Equal(t, "page.xhtml", root.File)
+	require.Len(t, root.Children, 3)
+	assert.Equal(t, "Fulfilment/page.xhtml", root.Children[0].File)
+	assert.Equal(t, "Orders/page.xhtml", root.Children[1].File)
+	assert.Equal(t, "stats/page.xhtml", root.Children[2].File)
+
+	for _, page := range append([]*ConfluencePage{&root}, root.Children...) {
+		b, err := afero.ReadFile(fs, "out/"+page.File)
+		require.NoError(t, err)
+		assert.NoError(t, validStorageFormat(string(b)), page.File)
+		assert.NotContains(t, string(b), "<details>")
+		assert.NotContains(t, string(b), "<script")
+	}
+	b, err = afero.ReadFile(fs, "out/Orders/page.xhtml")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `<ac:structured-macro ac:name="anchor"><ac:parameter ac:name="">Orders</ac:parameter>`)
+	assert.Contains(t, string(b), `<ac:structured-macro ac:name="expand">`)
+	assert.Contains(t, string(b), `<ac:image><ri:attachment ri:filename="diagram-0.svg"/></ac:image>`)
+	assert.NotContains(t, string(b), "noformat")
+	require.NotEmpty(t, root.Children[1].Attachments)
+	assert.True(t, strings.HasPrefix(root.Children[1].Attachments[0].URL, DefaultMermaidService+"/svg/"))
+	b, err = afero.ReadFile(fs, "out/page.xhtml")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `<ri:page ri:content-title="Orders"/>`)
+}
+
+func TestConfluenceStorageImages(t *testing.T) {
+	t.Parallel()
+
+	p := &Generator{PlantumlService: "https://plantuml.example.com"}
+	page := &ConfluencePage{}
+	out := p.confluenceStorage("pkg",
+		`<img src="https://plantuml.example.com/svg/~1abc"><img src="diagram.svg" alt="" /><img src="">`+
+			`<pre><code class="language-json">{&quot;a&quot;: 1}</code></pre>`, nil, page)
+	assert.Equal(t, `<ac:image><ri:attachment ri:filename="diagram-0.svg"/></ac:image>`+
+		`<ac:image><ri:attachment ri:filename="diagram.svg"/></ac:image>`+
+		`<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">json</ac:parameter>`+
+		`<ac:plain-text-body><![CDATA[{"a": 1}]]></ac:plain-text-body></ac:structured-macro>`, out)
+	assert.Equal(t, []ConfluenceAttachment{
+		{FileName: "diagram-0.svg", URL: "https://plantuml.example.com/svg/~1abc"},
+		{FileName: "diagram.svg", Path: "pkg/diagram.svg"},
+	}, page.Attachments)
+	assert.NoError(t, validStorageFormat(out))
+}
+
+func TestConfluenceStorageMermaid(t *testing.T) {
+	t.Parallel()
+
+	diagram := `<pre class="mermaid">graph TD
+A --&gt; B
+</pre>`
+	p := &Generator{MermaidService: "https://mermaid.example.com/"}
+	page := &ConfluencePage{}
+	out := p.confluenceStorage("pkg", diagram, nil, page)
+	assert.Equal(t, `<ac:image><ri:attachment ri:filename="diagram-0.svg"/></ac:image>`, out)
+	assert.Equal(t, []ConfluenceAttachment{{
+		FileName: "diagram-0.svg",
+		URL:      "https://mermaid.example.com/svg/" + base64.URLEncoding.EncodeToString([]byte("graph TD\nA --> B\n")),
+	}}, page.Attachments)
+
+	p.MermaidService = ""
+	page = &ConfluencePage{}
+	out = p.confluenceStorage("pkg", diagram, nil, page)
+	assert.Equal(t, `<ac:structured-macro ac:name="noformat"><ac:plain-text-body><![CDATA[graph TD
+A --> B
+]]></ac:plain-text-body></ac:structured-macro>`, out)
+	assert.Empty(t, page.Attachments)
+}
+
+func TestUniqueTitles(t *testing.T) {
+	t.Parallel()
+
+	titles := map[string]string{
+		"":                 "Shop",
+		"Sales":            "Sales",
+		"Sales/Payments":   "Payments",
+		"Stock":            "Stock",
+		"Stock/Payments":   "Payments",
+		"Stock/Orders":     "Orders",
+		"Other/A/Payments": "Payments",
+		"Other/B/Payments": "Payments",
+	}
+	uniqueTitles(titles)
+	assert.Equal(t, map[string]string{
+		"":                 "Shop",
+		"Sales":            "Sales",
+		"Sales/Payments":   "Payments (Sales)",
+		"Stock":            "Stock",
+		"Stock/Payments":   "Payments (Stock)",
+		"Stock/Orders":     "Orders",
+		"Other/A/Payments": "Payments (Other/A/Payments)",
+		"Other/B/Payments": "Payments (Other/B/Payments)",
+	}, titles)
+}
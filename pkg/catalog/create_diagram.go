@@ -133,30 +133,26 @@ func CreateFileName(dir string, elems ...string) (string, string) {
 	return path.Join(dir, absolutefilePath), dir
 }
 
+// getProjectApp returns the first ~project app of the root sysl files and a map of every package
+// included by the project apps of the roots to the macro package (project endpoint) that includes it.
 func (p *Generator) getProjectApp(m *sysl.Module) (*sysl.Application, map[string]string) {
 	includedProjects := Filter(
 		SortedKeys(m.Apps),
 		func(i string) bool {
-			if !syslutil.HasPattern(m.GetApps()[i].GetAttrs(), "project") {
-				return false
-			}
-			for _, ctx := range m.GetApps()[i].SourceContexts {
-				if path.Base(ctx.File) == path.Base(p.SourceFileName) {
-					return true
-				}
-			}
-			return false
+			return p.isRootProject(m.GetApps()[i])
 		},
 	)
 	if len(includedProjects) > 0 {
 		set := make(map[string]string)
-		app := m.GetApps()[includedProjects[0]]
-		for _, e := range app.GetEndpoints() {
-			if syslutil.HasPattern(e.GetAttrs(), "ignore") {
-				continue
-			}
-			for _, e2 := range e.GetStmt() {
-				set[e2.GetAction().GetAction()] = e.Name
+		for _, projectName := range includedProjects {
+			app := m.GetApps()[projectName]
+			for _, e := range app.GetEndpoints() {
+				if syslutil.HasPattern(e.GetAttrs(), "ignore") {
+					continue
+				}
+				for _, e2 := range e.GetStmt() {
+					set[e2.GetAction().GetAction()] = e.Name
+				}
 			}
 		}
 		return m.GetApps()[includedProjects[0]], set
@@ -206,26 +202,34 @@ func (p *Generator) ModuleAsMacroPackage(m *sysl.Module) map[string]*sysl.Module
 func (p *Generator) MacroPackages(module *sysl.Module) []string {
 	defer p.resetTempVars()
 	MacroPackages := p.ModuleAsMacroPackage(module)
+	if p.lazy() {
+		return SortedKeys(MacroPackages) // rendered when they're requested
+	}
 	for macroPackageName, macroPackage := range MacroPackages {
-		fileName := markdownName(p.OutputFileName, macroPackageName)
-		macroPackageFileName := path.Join(p.OutputDir, macroPackageName, fileName)
-		p.CurrentDir = macroPackageName
-		p.TempDir = macroPackageName // this is for p.Packages()
-		p.Title = macroPackageName
-		p.Links = map[string]string{
-			"Back": "../" + p.OutputFileName,
-		}
-		newGenerator := *p
-		newGenerator.Module = macroPackage
-		err := newGenerator.CreateMarkdown(newGenerator.Templates[1], macroPackageFileName, newGenerator)
-		if err != nil {
-			p.Log.Error("Error generating project table:", err)
-			os.Exit(1)
-		}
+		p.createMacroPackage(macroPackageName, macroPackage)
 	}
 	return SortedKeys(MacroPackages)
 }
 
+// createMacroPackage executes the markdown for a MacroPackage, and for its packages unless rendering lazily.
+func (p *Generator) createMacroPackage(macroPackageName string, macroPackage *sysl.Module) {
+	fileName := markdownName(p.OutputFileName, macroPackageName)
+	macroPackageFileName := path.Join(p.OutputDir, macroPackageName, fileName)
+	p.CurrentDir = macroPackageName
+	p.TempDir = macroPackageName // this is for p.Packages()
+	p.Title = macroPackageName
+	p.Links = map[string]string{
+		"Back": "../" + p.OutputFileName,
+	}
+	newGenerator := *p
+	newGenerator.Module = macroPackage
+	err := newGenerator.CreateMarkdown(newGenerator.Templates[1], macroPackageFileName, newGenerator)
+	if err != nil {
+		p.Log.Error("Error generating project table:", err)
+		os.Exit(1)
+	}
+}
+
 func (p *Generator) resetTempVars() {
 	p.CurrentDir = p.TempDir
 	p.TempDir = ""
@@ -235,24 +239,32 @@ func (p *Generator) resetTempVars() {
 func (p *Generator) Packages(m *sysl.Module) []string {
 	defer p.resetTempVars()
 	MacroPackages := p.ModuleAsPackages(m)
+	if p.lazy() {
+		return SortedKeys(MacroPackages) // rendered when they're requested
+	}
 	for packageName, pkg := range MacroPackages {
-		p.CurrentDir = path.Join(p.TempDir, packageName)
-		fileName := markdownName(p.OutputFileName, packageName)
-		fullOutputName := path.Join(p.OutputDir, p.CurrentDir, fileName)
-		// Add a synthetic package app to make the packageName available (unless the name is used).
-		for _, app := range pkg.Apps {
-			if app.Attrs == nil {
-				app.Attrs = make(map[string]*sysl.Attribute, 1)
-			}
-			app.Attrs[macropackage_name] = &sysl.Attribute{Attribute: &sysl.Attribute_S{S: packageName}}
-		}
-		if err := p.CreateMarkdown(p.Templates[len(p.Templates)-1], fullOutputName, pkg); err != nil {
-			p.Log.Error("error in generating "+fullOutputName, err)
-		}
+		p.createPackage(packageName, pkg)
 	}
 	return SortedKeys(MacroPackages)
 }
 
+// createPackage executes the markdown for a package in the MacroPackage being generated (TempDir).
+func (p *Generator) createPackage(packageName string, pkg *sysl.Module) {
+	p.CurrentDir = path.Join(p.TempDir, packageName)
+	fileName := markdownName(p.OutputFileName, packageName)
+	fullOutputName := path.Join(p.OutputDir, p.CurrentDir, fileName)
+	// Add a synthetic package app to make the packageName available (unless the name is used).
+	for _, app := range pkg.Apps {
+		if app.Attrs == nil {
+			app.Attrs = make(map[string]*sysl.Attribute, 1)
+		}
+		app.Attrs[macropackage_name] = &sysl.Attribute{Attribute: &sysl.Attribute_S{S: packageName}}
+	}
+	if err := p.CreateMarkdown(p.Templates[len(p.Templates)-1], fullOutputName, pkg); err != nil {
+		p.Log.Error("error in generating "+fullOutputName, err)
+	}
+}
+
 // ModuleAsPackages returns a map of [packagename]*sysl.Module
 func (p *Generator) ModuleAsPackages(m *sysl.Module) map[string]*sysl.Module {
 	packages := make(map[string]*sysl.Module)
@@ -273,7 +285,7 @@ func (p *Generator) ModuleAsPackages(m *sysl.Module) map[string]*sysl.Module {
 			}
 		}
 
-		packageName = GetPackageName(p.RootModule, app)
+		packageName = p.filteredPackageName(app)
 		if syslutil.HasPattern(app.GetAttrs(), "ignore") || syslutil.HasPattern(app.GetAttrs(), "project") {
 			continue
 		}
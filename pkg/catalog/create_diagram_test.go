@@ -34,7 +34,8 @@ App2:
 	assert.Nil(t, err)
 
 	gen := &Generator{RootModule: m, Fs: afero.NewMemMapFs(), FilesToCreate: map[string]string{}, Log: logrus.New()}
-	file := gen.IntegrationPlantuml(m, "", false)
+	file, err := gen.IntegrationPlantuml(m, "", false)
+	assert.Nil(t, err)
 	assert.Equal(t,
 		"/svg/~1UDgCpa5Bn30G1U1xViNpr5DXgo8UbhBTjegNBKZ5WqW9RMX2aqoPfAY8_rtMYWTFVSVv7ZEJR8v84cpARxLuQflx-bG_5crTeMog6ccAgi6fQL5N3-t5NtNpris_YaE8akFYhD1cK0XHiQBuCIiHUcaLd7n7TdDrUmsjpAYZ29FnisJfq9ERoIiVyIc0e-odaMdnGqcM67UMMDfdRQ8wA_6WU9MZbVqaW8APtjPHoSO5yk9Bl1JpdBr21dGxxFVQZDgUx-Rv3rskbFsZReSqpT5bug3yi3Zx7G00__yzoceA",
 		file)
@@ -204,7 +205,8 @@ func TestCreateIntegrationMermaid(t *testing.T) {
 	filePath := "../../tests/verysimple.sysl"
 	p, m, err := loadProject(filePath)
 	require.NoError(t, err)
-	mermaidString := p.IntegrationPlantuml(m, "", true)
+	mermaidString, err := p.IntegrationPlantuml(m, "", true)
+	require.NoError(t, err)
 	assert.NotNil(t, mermaidString)
 }
 
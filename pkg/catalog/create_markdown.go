@@ -16,6 +16,7 @@ import (
 
 var (
 	ofTypeSymbol = regexp.MustCompile(`(?m)(?:<:)(?:.*)`)
+	htmlImage    = regexp.MustCompile(`<img src="([^"]*)"[^>]*>`)
 )
 
 // CreateMarkdown is a wrapper function that also converts output markdown to html if in server mode
@@ -45,6 +46,7 @@ func (p *Generator) CreateMarkdown(t *template.Template, outputFileName string,
 		}
 		raw := converted.String()
 		raw = strings.ReplaceAll(raw, "README.md", p.OutputFileName)
+		raw = p.embedPlantuml(raw)
 		out = []byte(header + raw + style + endTags)
 	}
 	if _, err = f2.Write(out); err != nil {
@@ -52,3 +54,75 @@ func (p *Generator) CreateMarkdown(t *template.Template, outputFileName string,
 	}
 	return nil
 }
+
+// embedPlantuml embeds the PlantUML SVGs in html as objects instead of images, so the links of their elements
+// can be clicked. The links have to be absolute (see WithBaseURL) as they're relative to the PlantUML service.
+func (p *Generator) embedPlantuml(html string) string {
+	if p.PlantumlService == "" {
+		return html
+	}
+	return htmlImage.ReplaceAllStringFunc(html, func(s string) string {
+		src := htmlImage.FindStringSubmatch(s)[1]
+		if !strings.HasPrefix(src, p.PlantumlService+"/svg/") {
+			return s
+		}
+		return `<object type="image/svg+xml" data="` + src + `"></object>`
+	})
+}
+
+// keepPage returns a func that restores the page being generated (its directories, title and links), so
+// another page can be generated in the middle of it.
+func (p *Generator) keepPage() (restore func()) {
+	currentDir, tempDir, title, links := p.CurrentDir, p.TempDir, p.Title, p.Links
+	return func() { p.CurrentDir, p.TempDir, p.Title, p.Links = currentDir, tempDir, title, links }
+}
+
+// createPage generates page (relative to OutputDir) from tmpl with the title and links. data returns what the
+// template is executed with, computed in the directory of page so its links are relative to it, or nil if there's
+// nothing to generate. It returns whether page was created.
+func (p *Generator) createPage(page, title string, links map[string]string, tmpl string, data func() interface{}) bool {
+	defer p.keepPage()()
+	p.CurrentDir = path.Dir(page)
+	p.Title = title
+	p.Links = links
+	i := data()
+	if i == nil {
+		return false
+	}
+	t, err := template.New(path.Base(page)).Funcs(p.GetFuncMap()).Parse(tmpl)
+	if err != nil {
+		p.Log.Errorf("Error registering template of %s: %v", page, err)
+		return false
+	}
+	if err := p.CreateMarkdown(t, path.Join(p.OutputDir, page), i); err != nil {
+		p.Log.Errorf("Error creating %s: %v", page, err)
+		return false
+	}
+	return true
+}
+
+// packageDir returns whether dir (in OutputDir) is the directory of a MacroPackage or package page, whose
+// pages would overwrite the page of dir or be overwritten by it.
+func (p *Generator) packageDir(dir string) bool {
+	for page := range p.lazyPages() {
+		if strings.SplitN(page, "/", 2)[0] == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// createExtraPage generates the page of dir, which links back to the project page, and returns it (relative to
+// OutputDir), or "" if it wasn't created. See createPage.
+func (p *Generator) createExtraPage(dir, title, tmpl string, data func() interface{}) string {
+	if p.packageDir(dir) {
+		p.Log.Errorf("The %s page isn't generated as a package page is in the same directory: %s", title, dir)
+		return ""
+	}
+	page := path.Join(dir, markdownName(p.OutputFileName, dir))
+	links := map[string]string{"Back": "../" + markdownName(p.OutputFileName, path.Base(p.ProjectTitle))}
+	if !p.createPage(page, title, links, tmpl, data) {
+		return ""
+	}
+	return page
+}
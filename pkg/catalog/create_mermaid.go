@@ -20,6 +20,7 @@ func (p *Generator) IntegrationMermaid(m *sysl.Module, title string, EPA bool) s
 		return ""
 	}
 	mod := p.RootModule
+	pages := p.appPages()
 	if EPA {
 		result, err = endpointanalysisdiagram.GenerateMultipleAppEndpointAnalysisDiagram(mod, apps)
 
@@ -30,7 +31,10 @@ func (p *Generator) IntegrationMermaid(m *sysl.Module, title string, EPA bool) s
 		p.Log.Error(err)
 		return ""
 	}
-	return result
+	if !EPA {
+		result = p.styleMermaid(result, p.mermaidAppNodes(result))
+	}
+	return p.themeMermaid(p.linkMermaid(pages, result))
 }
 
 func (p *Generator) SequenceMermaid(appName string, endpoint *sysl.Endpoint) string {
@@ -40,7 +44,7 @@ func (p *Generator) SequenceMermaid(appName string, endpoint *sysl.Endpoint) str
 		p.Log.Error(error)
 		return ""
 	}
-	return result
+	return p.themeMermaid(result)
 }
 
 func (p *Generator) DataModelReturnMermaid(appName string, stmnt *sysl.Statement, endpoint *sysl.Endpoint) string {
@@ -65,7 +69,7 @@ func (p *Generator) DataModelMermaid(appName, typeName string) string {
 			return ""
 		}
 	}
-	return mermaidString
+	return p.themeMermaid(mermaidString)
 }
 
 func (p *Generator) DataModelAliasMermaid(app *sysl.Application, param Param) string {
@@ -80,5 +84,5 @@ func (p *Generator) DataModelAliasMermaid(app *sysl.Application, param Param) st
 
 func (p *Generator) DataModelAppMermaid(app *sysl.Application) string {
 	s, _ := datamodeldiagram.GenerateFullDataDiagram(&sysl.Module{Apps: map[string]*sysl.Application{"_": app}})
-	return s
+	return p.themeMermaid(s)
 }
@@ -9,15 +9,17 @@ import (
 	"github.com/anz-bank/sysl/pkg/diagrams"
 	"github.com/anz-bank/sysl/pkg/integrationdiagram"
 	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/pkg/errors"
 )
 
 // IntegrationPlantuml creates an integration diagram and returns the plantuml string
-func (p *Generator) IntegrationPlantuml(m *sysl.Module, title string, EPA bool) string {
+func (p *Generator) IntegrationPlantuml(m *sysl.Module, title string, EPA bool) (string, error) {
 	type intsCmd struct {
 		diagrams.Plantumlmixin
 		cmdutils.CmdContextParamIntgen
 	}
 	integration := intsCmd{}
+	pages := p.appPages()
 	projectApp := createProjectApp(m.Apps)
 	project := "__TEMP__"
 	defer delete(p.RootModule.GetApps(), project)
@@ -29,11 +31,10 @@ func (p *Generator) IntegrationPlantuml(m *sysl.Module, title string, EPA bool)
 	integration.Clustered = true
 	result, err := integrationdiagram.GenerateIntegrations(&integration.CmdContextParamIntgen, p.RootModule, p.Log)
 	if err != nil {
-		p.Log.Error("Error creating integration diagram:", err)
-		os.Exit(1)
+		return "", errors.Wrap(err, "Error creating integration diagram:")
 	}
-	plantumlString := result[integration.Output]
-	return PlantUMLURL(p.PlantumlService, plantumlString)
+	plantumlString := p.linkPlantuml(pages, result[integration.Output])
+	return p.plantumlURL(plantumlString), nil
 }
 
 // SequencePlantuml creates an sequence diagram and returns a plantuml url
@@ -46,7 +47,7 @@ func (p *Generator) SequencePlantuml(appName string, endpoint *sysl.Endpoint) st
 		os.Exit(1)
 		return ""
 	}
-	return PlantUMLURL(p.PlantumlService, plantumlString)
+	return p.plantumlURL(p.colorParticipants(plantumlString))
 
 }
 
@@ -73,11 +74,12 @@ func (p *Generator) DataModelParamPlantuml(app *sysl.Application, param Param) s
 // DataModelAppPlantuml generates a data model for all of the types in app and returns a plantuml url
 func (p *Generator) DataModelAppPlantuml(app *sysl.Application) string {
 	appName := GetAppNameString(app)
-	plantumlString := catalogdiagrams.GenerateDataModel(appName, catalogdiagrams.FromSyslTypeMap(appName, app.GetTypes()))
+	plantumlString := catalogdiagrams.GenerateThemedDataModel(appName,
+		catalogdiagrams.FromSyslTypeMap(appName, app.GetTypes()), p.Theme)
 	if _, ok := p.RootModule.GetApps()[appName]; !ok {
 		return ""
 	}
-	return PlantUMLURL(p.PlantumlService, plantumlString)
+	return p.plantumlURL(p.linkDataModel(appName, plantumlString))
 }
 
 func (p *Generator) DataModelPlantuml(appName, typeName string, t *sysl.Type, recursive bool) string {
@@ -95,7 +97,7 @@ func (p *Generator) DataModelAliasPlantuml(appName, typeName, typeAlias string,
 				typeAlias: catalogdiagrams.NewTypeData(typeAlias, NewTypeRef(appName, typeName)),
 			}, m,
 		)
-		plantumlString = catalogdiagrams.GenerateDataModel(appName, relatedTypes)
+		plantumlString = catalogdiagrams.GenerateThemedDataModel(appName, relatedTypes, p.Theme)
 		if _, ok := p.RootModule.GetApps()[appName]; !ok {
 			p.Log.Warnf("no app named %s", appName)
 			return ""
@@ -105,10 +107,11 @@ func (p *Generator) DataModelAliasPlantuml(appName, typeName, typeAlias string,
 			return ""
 		}
 	} else {
-		plantumlString = catalogdiagrams.GenerateDataModel(
+		plantumlString = catalogdiagrams.GenerateThemedDataModel(
 			appName,
 			map[string]*catalogdiagrams.TypeData{typeAlias: catalogdiagrams.NewTypeData(typeAlias, t)},
+			p.Theme,
 		)
 	}
-	return PlantUMLURL(p.PlantumlService, plantumlString)
+	return p.plantumlURL(p.linkDataModel(appName, plantumlString))
 }
@@ -61,19 +61,20 @@ func (p *Generator) DataModelTable(appName, typeName, aliasName string) string {
 			markdownTable += fmt.Sprintf("| %d | %s | %s |\n", k, simpleType.Enum[int64(k)], "")
 		}
 	case "tuple", "map", "relation":
-		for _, fieldName := range SortedKeys(simpleType.Properties) {
-			field := simpleType.Properties[fieldName]
-			switch simpleType.Properties[fieldName].Type {
+		for _, name := range SortedKeys(simpleType.Properties) {
+			field := simpleType.Properties[name]
+			fieldName := name + p.fieldDeprecationBadge(appName, typeName, name)
+			switch field.Type {
 			case "ref":
 				reference, ok := p.Mapper.SimpleTypes[field.Reference]
 				if !ok {
 					p.Log.Errorf("Unable to find type: %s with alias %s", field.Reference, fieldName)
 					markdownTable += fmt.Sprintf("| %s | %s | %s |\n", fieldName, field.Reference, field.Description)
 				} else {
-					markdownTable += fmt.Sprintf("| %s | %s (%s) | %s |\n", fieldName, convertReferenceToLink(field.Reference), reference.Type, field.Description)
+					markdownTable += fmt.Sprintf("| %s | %s (%s) | %s |\n", fieldName, p.convertReferenceToLink(field.Reference), reference.Type, field.Description)
 				}
 			case "list":
-				markdownTable += fmt.Sprintf("| %s | sequence of %s | %s |\n", fieldName, convertReferenceToLink(field.Items[0].Reference), field.Description)
+				markdownTable += fmt.Sprintf("| %s | sequence of %s | %s |\n", fieldName, p.convertReferenceToLink(field.Items[0].Reference), field.Description)
 			default:
 				markdownTable += fmt.Sprintf("| %s | %s | %s |\n", fieldName, field.Type, field.Description)
 			}
@@ -81,13 +82,13 @@ func (p *Generator) DataModelTable(appName, typeName, aliasName string) string {
 	case "list":
 		for _, field := range simpleType.Items {
 			if field.Type == "ref" {
-				markdownTable += fmt.Sprintf("| %s | sequence of %s | %s |\n", typeName, convertReferenceToLink(field.Reference), field.Description)
+				markdownTable += fmt.Sprintf("| %s | sequence of %s | %s |\n", typeName, p.convertReferenceToLink(field.Reference), field.Description)
 			} else {
 				markdownTable += fmt.Sprintf("| %s | sequence of %s | %s |\n", typeName, field.Type, field.Description)
 			}
 		}
 	case "ref":
-		markdownTable += fmt.Sprintf("| %s | %s | %s |\n", typeName, convertReferenceToLink(simpleType.Reference), simpleType.Description)
+		markdownTable += fmt.Sprintf("| %s | %s | %s |\n", typeName, p.convertReferenceToLink(simpleType.Reference), simpleType.Description)
 	}
 
 	return markdownTable
@@ -102,11 +103,32 @@ func printPrimitiveTable(aliasName, typeName string) (markdownTable string) {
 	return
 }
 
-func convertReferenceToLink(reference string) string {
-	safeReference := SanitiseOutputName(reference)
+// FieldTypeLink returns the type of a field of a type of appName as FieldType does, with a type reference linked
+// to the documentation of the type as in DataModelTable.
+func (p *Generator) FieldTypeLink(appName string, t *sysl.Type) string {
+	ref, prefix := t, ""
+	if sequence := t.GetSequence(); sequence != nil {
+		ref, prefix = sequence, "sequence of "
+	}
+	if ref.GetTypeRef() == nil {
+		return FieldType(t)
+	}
+	refPath := ref.GetTypeRef().GetRef().GetPath()
+	if appName := ref.GetTypeRef().GetRef().GetAppname(); len(appName.GetPart()) > 0 {
+		refPath = append([]string{JoinAppNameString(appName)}, refPath...)
+	}
+	if len(refPath) == 1 {
+		refPath = []string{appName, refPath[0]}
+	}
+	return prefix + p.convertReferenceToLink(strings.Join(refPath, "."))
+}
+
+// convertReferenceToLink returns a link to the documentation of a type reference ("App.Type"), which may be
+// on another page.
+func (p *Generator) convertReferenceToLink(reference string) string {
 	typeName := strings.Split(reference, ".")
 	if len(typeName) < 2 {
 		return "" // Not a valid reference
 	}
-	return fmt.Sprintf(`<a href="#%s">%s</a>`, safeReference, typeName[1])
+	return fmt.Sprintf(`<a href="%s">%s</a>`, p.typeLink(reference), typeName[1])
 }
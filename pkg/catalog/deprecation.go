@@ -0,0 +1,192 @@
+// deprecation.go: badges of deprecated apps, endpoints, types and fields, and a report of what's deprecated
+package catalog
+
+import (
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+const (
+	deprecationsDir = "deprecations" // The directory of the deprecation report in the output directory
+	DeprecatedColor = "LightGray"    // The color of deprecated apps and types in diagrams unless the theme has one for ~deprecated
+)
+
+// Deprecation is a deprecated app, endpoint ("App.Endpoint"), type ("App.Type") or field ("App.Type.field").
+type Deprecation struct {
+	Kind       string // Application, Endpoint, Type or Field
+	Name       string
+	Link       string // Its documentation, "" if it isn't documented
+	Sunset     string // The @sunset attribute
+	ReplacedBy string // The @replaced_by attribute
+}
+
+// DeprecatedCall is a call from an endpoint that isn't deprecated to one that is ("App.Endpoint").
+type DeprecatedCall struct {
+	From     string
+	FromLink string
+	To       string
+	ToLink   string
+}
+
+// Deprecations is what the deprecation report shows.
+type Deprecations struct {
+	Deprecated []Deprecation
+	Calls      []DeprecatedCall
+}
+
+// Deprecated returns whether a is tagged ~deprecated or has a @sunset or @replaced_by attribute.
+func Deprecated(a Attr) bool {
+	return syslutil.HasPattern(a.GetAttrs(), "deprecated") || attributeFold(a, "sunset", "replaced_by") != ""
+}
+
+// DeprecationBadge returns a badge for a if it's deprecated, with its sunset date and replacement, or "".
+func DeprecationBadge(a Attr) string {
+	if !Deprecated(a) {
+		return ""
+	}
+	badge := " <kbd>deprecated</kbd>"
+	if sunset := attributeFold(a, "sunset"); sunset != "" {
+		badge += " sunset " + sunset
+	}
+	if replacedBy := attributeFold(a, "replaced_by"); replacedBy != "" {
+		badge += " replaced by " + replacedBy
+	}
+	return badge
+}
+
+// fieldDeprecationBadge returns the DeprecationBadge of a field of a type of the root module.
+func (p *Generator) fieldDeprecationBadge(appName, typeName, fieldName string) string {
+	field, ok := typeFields(p.RootModule.GetApps()[appName].GetTypes()[typeName])[fieldName]
+	if !ok {
+		return ""
+	}
+	return DeprecationBadge(field)
+}
+
+// typeFields returns the fields of a tuple or relation type.
+func typeFields(t *sysl.Type) map[string]*sysl.Type {
+	if t.GetRelation() != nil {
+		return t.GetRelation().GetAttrDefs()
+	}
+	return t.GetTuple().GetAttrDefs()
+}
+
+// endpointDeprecated returns whether an endpoint of the root module, or its app, is deprecated.
+func (p *Generator) endpointDeprecated(appName, endpointName string) bool {
+	app, ok := p.RootModule.GetApps()[appName]
+	if !ok {
+		return false
+	}
+	if Deprecated(app) {
+		return true
+	}
+	endpoint, ok := app.GetEndpoints()[endpointName]
+	return ok && Deprecated(endpoint)
+}
+
+// Deprecations returns the deprecated apps, endpoints, types and fields of the root module, and the calls to
+// deprecated endpoints from endpoints that aren't. Links are relative to the page being generated.
+func (p *Generator) Deprecations() Deprecations {
+	var d Deprecations
+	pages := p.appPages()
+	deprecation := func(kind, name, link string, a Attr) {
+		if Deprecated(a) {
+			d.Deprecated = append(d.Deprecated, Deprecation{
+				Kind:       kind,
+				Name:       name,
+				Link:       link,
+				Sunset:     attributeFold(a, "sunset"),
+				ReplacedBy: attributeFold(a, "replaced_by"),
+			})
+		}
+	}
+	for _, appName := range SortedKeys(p.RootModule.GetApps()) {
+		app := p.RootModule.GetApps()[appName]
+		if syslutil.HasPattern(app.GetAttrs(), "ignore") {
+			continue
+		}
+		deprecation("Application", appName, p.appLink(pages, appName), app)
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			endpoint := app.GetEndpoints()[endpointName]
+			if syslutil.HasPattern(endpoint.GetAttrs(), "ignore") {
+				continue
+			}
+			deprecation("Endpoint", appName+"."+endpointName, p.endpointLink(pages, appName, endpointName), endpoint)
+		}
+		for _, typeName := range SortedKeys(app.GetTypes()) {
+			t := app.GetTypes()[typeName]
+			reference := appName + "." + typeName
+			var link string
+			if doc, ok := p.TypeDocs[reference]; ok {
+				link = p.relativeLink(doc.Page, doc.Anchor)
+			}
+			deprecation("Type", reference, link, t)
+			fields := typeFields(t)
+			for _, fieldName := range SortedKeys(fields) {
+				deprecation("Field", reference+"."+fieldName, link, fields[fieldName])
+			}
+		}
+	}
+
+	seen := make(map[DeprecatedCall]bool)
+	for _, appName := range SortedKeys(p.RootModule.GetApps()) {
+		app := p.RootModule.GetApps()[appName]
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			if p.endpointDeprecated(appName, endpointName) {
+				continue
+			}
+			forEachCall(app.GetEndpoints()[endpointName].GetStmt(), func(call *sysl.Call) {
+				target := JoinAppNameString(call.GetTarget())
+				if !p.endpointDeprecated(target, call.GetEndpoint()) {
+					return
+				}
+				c := DeprecatedCall{
+					From:     appName + "." + endpointName,
+					FromLink: p.endpointLink(pages, appName, endpointName),
+					To:       target + "." + call.GetEndpoint(),
+					ToLink:   p.endpointLink(pages, target, call.GetEndpoint()),
+				}
+				if !seen[c] {
+					seen[c] = true
+					d.Calls = append(d.Calls, c)
+				}
+			})
+		}
+	}
+	return d
+}
+
+// CreateDeprecationReport writes the deprecation report to deprecations/, and returns its page (relative to
+// OutputDir), or "" if nothing is deprecated.
+func (p *Generator) CreateDeprecationReport() string {
+	return p.createExtraPage(deprecationsDir, "Deprecations", DeprecationsTemplate, func() interface{} {
+		deprecations := p.Deprecations()
+		if len(deprecations.Deprecated) == 0 {
+			return nil
+		}
+		return struct {
+			*Generator
+			Deprecations Deprecations
+		}{p, deprecations}
+	})
+}
+
+// DeprecationsTemplate is the deprecation report.
+const DeprecationsTemplate = `
+{{/* Automatically generated by https://github.com/anz-bank/sysl-catalog it is strongly recommended not to edit this file */}}
+{{range $name, $link := .Links}} [{{$name}}]({{$link}}) | {{end}}
+# {{.Title}}
+
+| Kind | Name | Sunset | Replaced by |
+|----|----|----|----|{{range $d := .Deprecations.Deprecated}}
+| {{$d.Kind}} | {{if $d.Link}}[{{$d.Name}}]({{$d.Link}}){{else}}{{$d.Name}}{{end}} | {{$d.Sunset}} | {{$d.ReplacedBy}} |{{end}}
+
+## Calls to Deprecated Endpoints
+Endpoints that aren't deprecated but still call deprecated endpoints.
+{{if .Deprecations.Calls}}
+| Endpoint | Calls |
+|----|----|{{range $c := .Deprecations.Calls}}
+| {{if $c.FromLink}}[{{$c.From}}]({{$c.FromLink}}){{else}}{{$c.From}}{{end}} | {{if $c.ToLink}}[{{$c.To}}]({{$c.ToLink}}){{else}}{{$c.To}}{{end}} |{{end}}
+{{else}}
+<span style="color:grey">No calls to deprecated endpoints</span>
+{{end}}`
@@ -0,0 +1,84 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const deprecationSysl = `
+Orders:
+    @package = "Orders"
+    PlaceOrder:
+        Payments <- PayV1
+        Payments <- Pay
+    Cancel[~deprecated]:
+        Payments <- PayV1
+    !type Order:
+        id <: int
+        total <: int:
+            @replaced_by = "amount"
+        amount <: int
+Payments:
+    @package = "Payments"
+    PayV1:
+        @sunset = "2021-06-30"
+        @replaced_by = "Payments.Pay"
+        return ok
+    Pay: ...
+Legacy[~deprecated]:
+    @package = "Payments"
+    Get: ...
+`
+
+func TestDeprecations(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(deprecationSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out")
+	p.Run()
+
+	p.CurrentDir = deprecationsDir
+	d := p.Deprecations()
+	assert.Equal(t, []Deprecation{
+		{Kind: "Application", Name: "Legacy", Link: "../Payments/README.md#Legacy"},
+		{Kind: "Endpoint", Name: "Orders.Cancel", Link: "../Orders/README.md#Orders-Cancel"},
+		{Kind: "Field", Name: "Orders.Order.total", Link: "../Orders/README.md#Orders.Order", ReplacedBy: "amount"},
+		{Kind: "Endpoint", Name: "Payments.PayV1", Link: "../Payments/README.md#Payments-PayV1",
+			Sunset: "2021-06-30", ReplacedBy: "Payments.Pay"},
+	}, d.Deprecated)
+	assert.Equal(t, []DeprecatedCall{{
+		From:     "Orders.PlaceOrder",
+		FromLink: "../Orders/README.md#Orders-PlaceOrder",
+		To:       "Payments.PayV1",
+		ToLink:   "../Payments/README.md#Payments-PayV1",
+	}}, d.Calls)
+
+	b, err := afero.ReadFile(fs, "out/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "[Deprecations](deprecations/README.md)")
+	b, err = afero.ReadFile(fs, "out/deprecations/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b),
+		"| Endpoint | [Payments.PayV1](../Payments/README.md#Payments-PayV1) | 2021-06-30 | Payments.Pay |")
+	assert.Contains(t, string(b),
+		"| [Orders.PlaceOrder](../Orders/README.md#Orders-PlaceOrder) | [Payments.PayV1](../Payments/README.md#Payments-PayV1) |")
+
+	b, err = afero.ReadFile(fs, "out/Payments/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b),
+		"[PayV1](#Payments-PayV1) <kbd>deprecated</kbd> sunset 2021-06-30 replaced by Payments.Pay |")
+	assert.Contains(t, string(b), "</a>Application Legacy <kbd>deprecated</kbd>\n")
+	b, err = afero.ReadFile(fs, "out/Orders/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "| total <kbd>deprecated</kbd> replaced by amount | int |")
+
+	assert.Equal(t, DeprecatedColor, p.appColor("Legacy"))
+	assert.Equal(t, "", p.appColor("Payments"))
+}
@@ -0,0 +1,221 @@
+// document.go: renders every page of the catalog into a single standalone html document
+package catalog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+var (
+	documentLink    = regexp.MustCompile(`(href|src)="([^"#:]*\.md)(#[^"]*)?"`)
+	mermaidClick    = regexp.MustCompile(`(click \S+ )"([^"#:]*\.md)(#[^"]*)?"`)
+	documentAnchor  = regexp.MustCompile(`<a name=("[^"]*"|[^">]*)>`)
+	localLink       = regexp.MustCompile(`(href="|click \S+ ")#([^"]*)"`)
+	documentImage   = regexp.MustCompile(`src="(https?://[^"]+)"`)
+	documentHeading = regexp.MustCompile(`(?m)^# (.+)$`)
+	mermaidScript   = regexp.MustCompile(`<script src="[^"]*mermaid[^"]*"></script>`)
+)
+
+// documentPage is one page of the catalog in a single document.
+type documentPage struct {
+	Dir   string // The directory of the page relative to the output directory
+	Title string
+	Body  string
+}
+
+// documentID returns the anchor of the page in dir.
+func documentID(dir string) string {
+	if dir == "" || dir == "." {
+		return "page"
+	}
+	return "page-" + SanitiseOutputName(strings.ReplaceAll(dir, "/", "-"))
+}
+
+// markdownPages renders every page as markdown into memory and returns the directories of the pages relative
+// to the output directory, in the order the pages link to each other (project, macro packages, packages).
+func (p *Generator) markdownPages() (afero.Fs, []string, error) {
+	fs, outputDir, format, outputFileName := p.Fs, p.OutputDir, p.Format, p.OutputFileName
+	defer func() {
+		p.Fs, p.OutputDir, p.Format, p.OutputFileName = fs, outputDir, format, outputFileName
+	}()
+	pages := afero.NewMemMapFs()
+	p.Fs, p.OutputDir, p.Format, p.OutputFileName = pages, "/", "markdown", outputFileNames["markdown"]
+	p.Run()
+
+	var dirs []string
+	if err := afero.Walk(pages, "/", func(filename string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && path.Base(filename) == p.OutputFileName {
+			dirs = append(dirs, strings.TrimPrefix(path.Dir(filename), "/"))
+		}
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+	// Each page comes before the pages in its subdirectories.
+	sort.Slice(dirs, func(i, j int) bool {
+		a, b := strings.Split(dirs[i], "/"), strings.Split(dirs[j], "/")
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return pages, dirs, nil
+}
+
+// readMarkdownPage returns the markdown of the page in dir and its title, the first heading of the page.
+func readMarkdownPage(pages afero.Fs, dir string) (raw []byte, title string, err error) {
+	raw, err = afero.ReadFile(pages, path.Join("/", dir, outputFileNames["markdown"]))
+	if err != nil {
+		return nil, "", err
+	}
+	title = dir
+	if m := documentHeading.FindSubmatch(raw); m != nil {
+		title = strings.TrimSpace(string(m[1]))
+	}
+	return mermaidScript.ReplaceAll(raw, nil), title, nil
+}
+
+// RunDocument generates every page as markdown and combines them into OutputDir/index.html with a table of
+// contents. Links between pages are rewritten to anchors in the document and plantuml diagrams are inlined.
+func (p *Generator) RunDocument() error {
+	pages, dirs, err := p.markdownPages()
+	if err != nil {
+		return err
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+	)
+	var docPages []documentPage
+	for _, dir := range dirs {
+		raw, title, err := readMarkdownPage(pages, dir)
+		if err != nil {
+			return err
+		}
+		page := documentPage{Dir: dir, Title: title}
+		var converted bytes.Buffer
+		if err := md.Convert(raw, &converted); err != nil {
+			return errors.Wrap(err, "Error converting markdown to html:")
+		}
+		page.Body = p.documentLinks(dir, converted.String())
+		docPages = append(docPages, page)
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Replace(header, "<title>Sysl Catalog</title>",
+		"<title>"+html.EscapeString(path.Base(p.ProjectTitle))+"</title>", 1))
+	b.WriteString(`<script src="https://cdn.jsdelivr.net/npm/mermaid/dist/mermaid.min.js"></script>` + "\n")
+	b.WriteString("<nav id=\"toc\">\n<h1>Contents</h1>\n<ul>\n")
+	for _, page := range docPages {
+		depth := 0
+		if page.Dir != "" {
+			depth = strings.Count(page.Dir, "/") + 1
+		}
+		fmt.Fprintf(&b, "<li style=\"margin-left: %dem\"><a href=\"#%s\">%s</a></li>\n",
+			2*depth, documentID(page.Dir), html.EscapeString(page.Title))
+	}
+	b.WriteString("</ul>\n</nav>\n")
+	for _, page := range docPages {
+		fmt.Fprintf(&b, "<section class=\"page\" id=\"%s\">\n%s\n</section>\n", documentID(page.Dir), page.Body)
+	}
+	b.WriteString(style + documentStyle + endTags)
+
+	out := p.inlineDiagrams(b.String())
+	if err := p.Fs.MkdirAll(p.OutputDir, os.ModePerm); err != nil {
+		return err
+	}
+	return afero.WriteFile(p.Fs, path.Join(p.OutputDir, outputFileNames["document"]), []byte(out), 0644)
+}
+
+// documentLinks rewrites links from the page in dir to other pages into anchors in the document, and opens
+// every <details> so that collapsed sections are printed. The anchors of each page are prefixed with the id of
+// the page, as pages have anchors with the same names.
+func (p *Generator) documentLinks(dir, page string) string {
+	anchor := func(target, fragment string) string {
+		id := documentID(path.Dir(path.Join(dir, target)))
+		if fragment != "" {
+			return "#" + id + "-" + strings.TrimPrefix(fragment, "#")
+		}
+		return "#" + id
+	}
+	page = documentAnchor.ReplaceAllStringFunc(page, func(s string) string {
+		name := strings.Trim(documentAnchor.FindStringSubmatch(s)[1], `"`)
+		return fmt.Sprintf(`<a name="%s-%s">`, documentID(dir), name)
+	})
+	page = localLink.ReplaceAllStringFunc(page, func(s string) string {
+		m := localLink.FindStringSubmatch(s)
+		return fmt.Sprintf(`%s#%s-%s"`, m[1], documentID(dir), m[2])
+	})
+	page = documentLink.ReplaceAllStringFunc(page, func(s string) string {
+		m := documentLink.FindStringSubmatch(s)
+		return fmt.Sprintf(`%s="%s"`, m[1], anchor(m[2], m[3]))
+	})
+	page = mermaidClick.ReplaceAllStringFunc(page, func(s string) string {
+		m := mermaidClick.FindStringSubmatch(s)
+		return fmt.Sprintf(`%s"%s"`, m[1], anchor(m[2], m[3]))
+	})
+	return strings.ReplaceAll(page, "<details>", "<details open>")
+}
+
+// inlineDiagrams replaces the plantuml diagram urls in page with the diagrams themselves so that the document
+// doesn't depend on the plantuml service. Diagrams that can't be fetched are left as urls.
+func (p *Generator) inlineDiagrams(page string) string {
+	if p.PlantumlService == "" {
+		return page
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	fetched := make(map[string]string)
+	return documentImage.ReplaceAllStringFunc(page, func(s string) string {
+		url := html.UnescapeString(documentImage.FindStringSubmatch(s)[1])
+		if !strings.HasPrefix(url, p.PlantumlService) {
+			return s
+		}
+		if inlined, ok := fetched[url]; ok {
+			return inlined
+		}
+		fetched[url] = s
+		resp, err := client.Get(url)
+		if err != nil {
+			p.Log.Warn("Error fetching diagram: ", err)
+			return s
+		}
+		defer resp.Body.Close()
+		svg, err := ioutil.ReadAll(resp.Body)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			p.Log.Warnf("Error fetching diagram %s: %s %v", url, resp.Status, err)
+			return s
+		}
+		fetched[url] = `src="data:image/svg+xml;base64,` + base64.StdEncoding.EncodeToString(svg) + `"`
+		return fetched[url]
+	})
+}
+
+const documentStyle = `
+<style type="text/css">
+#toc ul { list-style: none; padding-left: 0; }
+section.page { border-top: 1px solid #eaecef; margin-top: 2em; }
+@media print {
+  #toc { page-break-after: always; }
+  section.page { page-break-before: always; border-top: none; }
+}
+</style>
+`
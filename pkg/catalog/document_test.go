@@ -0,0 +1,77 @@
+package catalog
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDocument(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("test", "", "document", logrus.New(), m, fs, "out")
+	p.Run()
+
+	files, err := afero.ReadDir(fs, "out")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	doc, err := afero.ReadFile(fs, "out/index.html")
+	require.NoError(t, err)
+	out := string(doc)
+
+	assert.Contains(t, out, `<nav id="toc">`)
+	assert.Contains(t, out, `<li style="margin-left: 0em"><a href="#page">`)
+	assert.Contains(t, out, `<li style="margin-left: 2em"><a href="#page-Orders">`)
+	assert.Contains(t, out, `<section class="page" id="page-Fulfilment">`)
+	assert.Contains(t, out, `href="#page-Orders"`)
+	assert.NotContains(t, out, "README.md")
+	assert.Equal(t, "document", p.Format)
+}
+
+func TestDocumentLinks(t *testing.T) {
+	t.Parallel()
+
+	p := &Generator{}
+	page := `<a href="../Orders/README.md#Orders">Orders</a> <a href="Fulfilment/README.md">Fulfilment</a>
+click Orders "../Orders/README.md"
+<a name=Shipping></a><a href="#Shipping">Shipping</a>
+<a href="https://example.com/README.md">external</a><details>`
+	assert.Equal(t, `<a href="#page-Orders-Orders">Orders</a> <a href="#page-Shipping-Fulfilment">Fulfilment</a>
+click Orders "#page-Orders"
+<a name="page-Shipping-Shipping"></a><a href="#page-Shipping-Shipping">Shipping</a>
+<a href="https://example.com/README.md">external</a><details open>`, p.documentLinks("Shipping", page))
+}
+
+func TestDocumentLinksSharedAnchors(t *testing.T) {
+	t.Parallel()
+
+	// Two packages with an anchor of the same name keep their anchors apart, and links go to the right one.
+	p := &Generator{}
+	orders := p.documentLinks("Orders", `<a name=Get></a><a href="../Shipping/README.md#Get">Shipping</a>`)
+	shipping := p.documentLinks("Shipping", `<a name=Get></a><a href="../Orders/README.md#Get">Orders</a>`)
+	assert.Equal(t, `<a name="page-Orders-Get"></a><a href="#page-Shipping-Get">Shipping</a>`, orders)
+	assert.Equal(t, `<a name="page-Shipping-Get"></a><a href="#page-Orders-Get">Orders</a>`, shipping)
+}
+
+func TestInlineDiagrams(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<svg/>")
+	}))
+	defer server.Close()
+	p := &Generator{PlantumlService: server.URL, Log: logrus.New()}
+	page := `<img src="` + server.URL + `/svg/~1abc"> <img src="https://example.com/a.svg">`
+	assert.Equal(t, `<img src="data:image/svg+xml;base64,PHN2Zy8+"> <img src="https://example.com/a.svg">`,
+		p.inlineDiagrams(page))
+}
@@ -0,0 +1,199 @@
+// example.go: example JSON payloads of the request and response types of endpoints
+package catalog
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslwrapper"
+)
+
+// primitiveExample returns an example of a primitive type (in lower case), and whether it's primitive.
+func primitiveExample(typeName string) (interface{}, bool) {
+	switch typeName {
+	case "bool":
+		return true, true
+	case "int", "int32", "int64":
+		return 0, true
+	case "float", "float32", "float64", "double", "decimal":
+		return 0.0, true
+	case "string", "string_8":
+		return "string", true
+	case "bytes":
+		return "Ynl0ZXM=", true
+	case "date":
+		return "2006-01-02", true
+	case "datetime":
+		return "2006-01-02T15:04:05Z", true
+	case "uuid":
+		return "123e4567-e89b-12d3-a456-426614174000", true
+	case "xml":
+		return "<xml/>", true
+	case "any":
+		return nil, true
+	}
+	return nil, false
+}
+
+// exampleAttr returns the @example attribute of t, parsed as JSON if it's valid JSON, and whether t has one.
+func exampleAttr(t *sysl.Type) (interface{}, bool) {
+	s := Attribute(t, "example")
+	if s == "" {
+		return nil, false
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		return v, true
+	}
+	return s, true
+}
+
+// exampleJSON formats an example value as indented JSON.
+func exampleJSON(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// ExampleJSON returns an example JSON payload of a type, or "" if the type isn't known.
+func (p *Generator) ExampleJSON(appName, typeName string) string {
+	v, ok := p.exampleReference(appName+"."+typeName, make(map[string]bool))
+	if !ok {
+		return ""
+	}
+	return exampleJSON(v)
+}
+
+// ExampleParamJSON returns an example JSON payload of a param of an endpoint of app, or "".
+func (p *Generator) ExampleParamJSON(app *sysl.Application, param Param) string {
+	v, ok := p.exampleOfSyslType(JoinAppNameString(app.GetName()), param.GetType())
+	if !ok {
+		return ""
+	}
+	return exampleJSON(v)
+}
+
+// ExampleReturnJSON returns an example JSON payload of the type returned by a return statement, or "".
+func (p *Generator) ExampleReturnJSON(appName string, stmt *sysl.Statement) string {
+	ret := stmt.GetRet()
+	if ret == nil {
+		return ""
+	}
+	t := strings.TrimSpace(strings.ReplaceAll(ofTypeSymbol.FindString(ret.GetPayload()), "<: ", ""))
+	if t == "" {
+		return ""
+	}
+	sequence := strings.HasPrefix(t, "sequence of ")
+	t = strings.TrimPrefix(t, "sequence of ")
+	var v interface{}
+	var ok bool
+	if v, ok = primitiveExample(strings.ToLower(t)); !ok {
+		if !strings.Contains(t, ".") {
+			t = appName + "." + t
+		}
+		if v, ok = p.exampleReference(t, make(map[string]bool)); !ok {
+			return ""
+		}
+	}
+	if sequence {
+		v = []interface{}{v}
+	}
+	return exampleJSON(v)
+}
+
+// CodeBlock returns a fenced markdown code block, which templates can't write in a Go raw string.
+func CodeBlock(language, code string) string {
+	return "```" + language + "\n" + code + "\n```"
+}
+
+// exampleOfSyslType returns an example of a sysl type of app (a param), and whether it's known.
+func (p *Generator) exampleOfSyslType(appName string, t *sysl.Type) (interface{}, bool) {
+	if v, ok := exampleAttr(t); ok {
+		return v, true
+	}
+	switch {
+	case t.GetPrimitive() != sysl.Type_NO_Primitive:
+		return primitiveExample(strings.ToLower(t.GetPrimitive().String()))
+	case t.GetSequence() != nil:
+		v, ok := p.exampleOfSyslType(appName, t.GetSequence())
+		return []interface{}{v}, ok
+	case t.GetTypeRef() != nil:
+		refAppName, typeName := GetAppTypeName(&sysl.Param{Type: t})
+		if refAppName == "" {
+			refAppName = appName
+		}
+		if refAppName == "primitive" {
+			return primitiveExample(strings.ToLower(typeName))
+		}
+		return p.exampleReference(refAppName+"."+typeName, make(map[string]bool))
+	}
+	return nil, false
+}
+
+// exampleReference returns an example of a type ("App.Type") from the simple types of the mapper, and
+// whether it's known. A type that refers to itself is null where it recurses.
+func (p *Generator) exampleReference(reference string, seen map[string]bool) (interface{}, bool) {
+	if p.Mapper == nil {
+		return nil, false
+	}
+	simpleType, ok := p.Mapper.SimpleTypes[reference]
+	if !ok {
+		return nil, false
+	}
+	if seen[reference] {
+		return nil, true
+	}
+	seen[reference] = true
+	defer delete(seen, reference)
+	var syslType *sysl.Type
+	if parts := strings.SplitN(reference, ".", 2); len(parts) == 2 {
+		syslType = p.RootModule.GetApps()[parts[0]].GetTypes()[parts[1]]
+	}
+	return p.exampleValue(simpleType, syslType, seen), true
+}
+
+// exampleValue returns an example of a simple type, preferring the @example attributes of the sysl type
+// it's made from (nil if unknown) and of its fields.
+func (p *Generator) exampleValue(t *syslwrapper.Type, syslType *sysl.Type, seen map[string]bool) interface{} {
+	if v, ok := exampleAttr(syslType); ok {
+		return v
+	}
+	switch t.Type {
+	case "ref":
+		v, _ := p.exampleReference(t.Reference, seen)
+		return v
+	case "list":
+		if len(t.Items) == 0 {
+			return []interface{}{}
+		}
+		return []interface{}{p.exampleValue(t.Items[0], syslType.GetSequence(), seen)}
+	case "map":
+		if len(t.Items) < 2 {
+			return map[string]interface{}{}
+		}
+		return map[string]interface{}{"key": p.exampleValue(t.Items[1], nil, seen)}
+	case "enum":
+		var keys []int64
+		for k := range t.Enum {
+			keys = append(keys, k)
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		return t.Enum[keys[0]]
+	case "tuple", "relation":
+		fields := typeFields(syslType)
+		object := make(map[string]interface{}, len(t.Properties))
+		for name, field := range t.Properties {
+			object[name] = p.exampleValue(field, fields[name], seen)
+		}
+		return object
+	}
+	v, _ := primitiveExample(strings.ToLower(t.Type))
+	return v
+}
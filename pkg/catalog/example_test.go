@@ -0,0 +1,74 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exampleSysl = `
+Orders:
+    @package = "Orders"
+    PlaceOrder(order <: Order):
+        return ok <: Receipt
+    ListOrders:
+        return ok <: sequence of Order
+    !type Order:
+        id <: int
+        customer <: string:
+            @example = "Jane Citizen"
+        status <: Status
+        lines <: sequence of Line
+        parent <: Order
+    !type Line:
+        sku <: string
+        quantity <: int:
+            @example = "3"
+    !type Receipt:
+        @example = "{\"id\": 42}"
+        id <: int
+    !enum Status:
+        open: 1
+        closed: 2
+`
+
+func TestExampleJSON(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(exampleSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out")
+	p.Run()
+
+	order := `{
+  "customer": "Jane Citizen",
+  "id": 0,
+  "lines": [
+    {
+      "quantity": 3,
+      "sku": "string"
+    }
+  ],
+  "parent": null,
+  "status": "open"
+}`
+	assert.Equal(t, order, p.ExampleJSON("Orders", "Order"))
+	assert.Equal(t, "", p.ExampleJSON("Orders", "Missing"))
+
+	orders := m.GetApps()["Orders"]
+	placeOrder := orders.GetEndpoints()["PlaceOrder"]
+	assert.Equal(t, order, p.ExampleParamJSON(orders, placeOrder.GetParam()[0]))
+	assert.Equal(t, "{\n  \"id\": 42\n}", p.ExampleReturnJSON("Orders", placeOrder.GetStmt()[0]))
+	listOrders := orders.GetEndpoints()["ListOrders"]
+	assert.Contains(t, p.ExampleReturnJSON("Orders", listOrders.GetStmt()[0]), "[\n  {\n    \"customer\": \"Jane Citizen\",")
+
+	b, err := afero.ReadFile(fs, "out/Orders/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "<summary>Example</summary>\n\n```json\n{\n  \"id\": 42\n}\n```\n</details>")
+	assert.Contains(t, string(b), "```json\n"+order+"\n```")
+}
@@ -0,0 +1,203 @@
+// export.go: exports of the architecture (apps grouped by project and package, and their dependencies)
+package catalog
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// exporters are the export formats, the file each is written to in the output directory and what writes it.
+var exporters = map[string]struct {
+	fileName string
+	export   func(*Generator) string
+}{
+	"dot":         {"architecture.dot", (*Generator).ExportDot},
+	"structurizr": {"workspace.dsl", (*Generator).ExportStructurizr},
+}
+
+// ExportFileName returns the file an export format is written to in the output directory, or "" if it's unknown.
+func ExportFileName(format string) string {
+	return exporters[format].fileName
+}
+
+// System is a ~project endpoint (or the whole module when there is no project app) in an export.
+type System struct {
+	Name       string
+	Containers []Container
+}
+
+// Container is a package of apps in an export.
+type Container struct {
+	Name       string
+	Components []Component
+}
+
+// Component is an app in an export.
+type Component struct {
+	ID          string // The system and app names, as an app is in every system that has its package
+	Name        string
+	Description string
+	Properties  map[string]string // Owner attributes of the app
+}
+
+// Architecture returns the apps of the root module grouped into systems and containers along with the
+// dependencies between them, from component ID to component ID. A call from an app is to the callee in the same
+// system, or to every system the callee is in if it isn't in that one.
+func (p *Generator) Architecture() ([]System, []Dependency) {
+	if p.RootModule == nil {
+		return nil, nil
+	}
+	macroPackages := p.ModuleAsMacroPackage(p.RootModule)
+	if p.StartTemplateIndex != 0 || len(macroPackages) == 0 {
+		macroPackages = map[string]*sysl.Module{path.Base(p.ProjectTitle): p.RootModule}
+	}
+	var systems []System
+	systemOf := make(map[string]string) // The system of each component ID
+	ids := make(map[string][]string)    // The IDs of the components of each app
+	for _, systemName := range SortedKeys(macroPackages) {
+		system := System{Name: systemName}
+		packages := p.ModuleAsPackages(macroPackages[systemName])
+		for _, packageName := range SortedKeys(packages) {
+			container := Container{Name: packageName}
+			for _, appName := range SortedKeys(packages[packageName].GetApps()) {
+				app := packages[packageName].GetApps()[appName]
+				component := Component{
+					ID:          systemName + "/" + appName,
+					Name:        appName,
+					Description: Attribute(app, "description"),
+					Properties:  map[string]string{},
+				}
+				for attrName := range app.GetAttrs() {
+					if strings.HasPrefix(strings.ToLower(attrName), "owner") {
+						component.Properties[attrName] = Attribute(app, attrName)
+					}
+				}
+				container.Components = append(container.Components, component)
+				ids[appName] = append(ids[appName], component.ID)
+				systemOf[component.ID] = systemName
+			}
+			system.Containers = append(system.Containers, container)
+		}
+		systems = append(systems, system)
+	}
+	var deps []Dependency
+	_, all := p.dependencies(p.RootModule)
+	for _, dep := range all {
+		for _, from := range ids[dep.From] {
+			var to []string
+			for _, id := range ids[dep.To] {
+				if systemOf[id] == systemOf[from] {
+					to = []string{id}
+					break
+				}
+				to = append(to, id)
+			}
+			for _, id := range to {
+				deps = append(deps, Dependency{From: from, To: id})
+			}
+		}
+	}
+	return systems, deps
+}
+
+// ExportDot returns the architecture as a Graphviz DOT graph; systems and containers are clusters.
+func (p *Generator) ExportDot() string {
+	systems, deps := p.Architecture()
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n    rankdir=LR;\n    node [shape=box];\n", quote(path.Base(p.ProjectTitle)))
+	for i, system := range systems {
+		fmt.Fprintf(&b, "    subgraph \"cluster_%d\" {\n        label=%s;\n", i, quote(system.Name))
+		for j, container := range system.Containers {
+			fmt.Fprintf(&b, "        subgraph \"cluster_%d_%d\" {\n            label=%s;\n", i, j, quote(container.Name))
+			for _, component := range container.Components {
+				attrs := []string{"label=" + quote(component.Name)}
+				if component.Description != "" {
+					attrs = append(attrs, "tooltip="+quote(component.Description))
+				}
+				for _, key := range SortedKeys(component.Properties) {
+					attrs = append(attrs, quote(key)+"="+quote(component.Properties[key]))
+				}
+				fmt.Fprintf(&b, "            %s [%s];\n", quote(component.ID), strings.Join(attrs, ", "))
+			}
+			b.WriteString("        }\n")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, dep := range deps {
+		fmt.Fprintf(&b, "    %s -> %s;\n", quote(dep.From), quote(dep.To))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// ExportStructurizr returns the architecture as a Structurizr DSL workspace with a view for each level.
+func (p *Generator) ExportStructurizr() string {
+	systems, deps := p.Architecture()
+	ids := make(map[string]string)
+	var views []string
+	var b strings.Builder
+	fmt.Fprintf(&b, "workspace %s {\n    model {\n", quote(path.Base(p.ProjectTitle)))
+	for i, system := range systems {
+		systemID := fmt.Sprintf("s%d", i)
+		fmt.Fprintf(&b, "        %s = softwareSystem %s {\n", systemID, quote(system.Name))
+		views = append(views, fmt.Sprintf("container %s {", systemID))
+		for j, container := range system.Containers {
+			containerID := fmt.Sprintf("s%dc%d", i, j)
+			fmt.Fprintf(&b, "            %s = container %s {\n", containerID, quote(container.Name))
+			views = append(views, fmt.Sprintf("component %s {", containerID))
+			for _, component := range container.Components {
+				ids[component.ID] = fmt.Sprintf("a%d", len(ids))
+				fmt.Fprintf(&b, "                %s = component %s %s {\n",
+					ids[component.ID], quote(component.Name), quote(component.Description))
+				if len(component.Properties) > 0 {
+					b.WriteString("                    properties {\n")
+					for _, key := range SortedKeys(component.Properties) {
+						fmt.Fprintf(&b, "                        %s %s\n", quote(key), quote(component.Properties[key]))
+					}
+					b.WriteString("                    }\n")
+				}
+				b.WriteString("                }\n")
+			}
+			b.WriteString("            }\n")
+		}
+		b.WriteString("        }\n")
+	}
+	for _, dep := range deps {
+		fmt.Fprintf(&b, "        %s -> %s \"Calls\"\n", ids[dep.From], ids[dep.To])
+	}
+	b.WriteString("    }\n    views {\n        systemLandscape {\n            include *\n            autoLayout\n        }\n")
+	for _, view := range views {
+		fmt.Fprintf(&b, "        %s\n            include *\n            autoLayout\n        }\n", view)
+	}
+	b.WriteString("    }\n}\n")
+	return b.String()
+}
+
+// Export writes the architecture in each of formats ("dot" or "structurizr") to the output directory.
+func (p *Generator) Export(formats ...string) error {
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+		exporter, ok := exporters[format]
+		if !ok {
+			return errors.Errorf("unknown export format %q", format)
+		}
+		fileName := path.Join(p.OutputDir, exporter.fileName)
+		if err := afero.WriteFile(p.Fs, fileName, []byte(exporter.export(p)), 0644); err != nil {
+			return errors.Wrap(err, "error writing "+fileName)
+		}
+	}
+	return nil
+}
+
+// quote returns s as a double quoted string.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s) + `"`
+}
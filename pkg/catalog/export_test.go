@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportSysl = `
+Orders:
+    @package = "Orders"
+    @owner.email = "orders@example.com"
+    @description = "Takes orders"
+    PlaceOrder:
+        Shipping <- Ship
+
+Shipping:
+    @package = "Fulfilment"
+    Ship: ...
+`
+
+func TestExportDot(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(exportSysl)
+	require.NoError(t, err)
+	p := NewProject("test.sysl", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	dot := p.ExportDot()
+	assert.Contains(t, dot, `label="Fulfilment";`)
+	assert.Contains(t, dot, `"test.sysl/Orders" [label="Orders", tooltip="Takes orders", "owner.email"="orders@example.com"];`)
+	assert.Contains(t, dot, `"test.sysl/Orders" -> "test.sysl/Shipping";`)
+}
+
+func TestExportStructurizr(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(exportSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("test.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out")
+
+	require.NoError(t, p.Export("structurizr", "dot"))
+	dsl, err := afero.ReadFile(fs, "out/workspace.dsl")
+	require.NoError(t, err)
+	assert.Contains(t, string(dsl), `s0 = softwareSystem "test.sysl" {`)
+	assert.Contains(t, string(dsl), `= component "Orders" "Takes orders" {`)
+	assert.Contains(t, string(dsl), `"owner.email" "orders@example.com"`)
+	assert.Contains(t, string(dsl), `a1 -> a0 "Calls"`)
+	exists, err := afero.Exists(fs, "out/architecture.dot")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.Error(t, p.Export("svg"))
+	assert.Equal(t, "workspace.dsl", ExportFileName("structurizr"))
+}
+
+const systemsExportSysl = `
+Shop[~project]:
+    Sales:
+        Orders
+
+Warehouse[~project]:
+    Stock:
+        Shared
+
+Orders:
+    @package = "Orders"
+    PlaceOrder:
+        Payments <- Pay
+
+Payments:
+    @package = "Shared"
+    Pay: ...
+`
+
+func TestExportSystems(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "test.sysl", []byte(systemsExportSysl), 0644))
+	m, err := parse.NewParser().Parse("test.sysl", AferoRetriever{fs})
+	require.NoError(t, err)
+	p := NewProject("test.sysl", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	systems, deps := p.Architecture()
+	var ids []string
+	for _, system := range systems {
+		for _, container := range system.Containers {
+			for _, component := range container.Components {
+				ids = append(ids, component.ID)
+			}
+		}
+	}
+	assert.Equal(t, []string{"Sales/Orders", "Stock/Payments"}, ids)
+	assert.Equal(t, []Dependency{{From: "Sales/Orders", To: "Stock/Payments"}}, deps)
+
+	dot := p.ExportDot()
+	assert.Contains(t, dot, `"Stock/Payments" [label="Payments"];`)
+	assert.Contains(t, dot, `"Sales/Orders" -> "Stock/Payments";`)
+	assert.Contains(t, p.ExportStructurizr(), `a0 -> a1 "Calls"`)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/Masterminds/sprig"
 	"github.com/anz-bank/gop/pkg/gop"
+	"github.com/anz-bank/sysl-catalog/pkg/catalogdiagrams"
 	"github.com/anz-bank/sysl/pkg/sysl"
 	"github.com/anz-bank/sysl/pkg/syslutil"
 	"github.com/anz-bank/sysl/pkg/syslwrapper"
@@ -21,9 +22,11 @@ import (
 )
 
 var outputFileNames = map[string]string{
-	"md":       "README.md",
-	"markdown": "README.md",
-	"html":     "index.html",
+	"md":         "README.md",
+	"markdown":   "README.md",
+	"html":       "index.html",
+	"document":   "index.html",
+	"confluence": "page.xhtml",
 }
 
 // Generator is the contextual object that is used in the markdown generation
@@ -34,6 +37,9 @@ type Generator struct {
 	RedocFilesToCreate   map[string]string
 	GeneratedFiles       map[string][]byte
 	SourceFileName       string
+	SourceFileNames      []string          // All root sysl files when generating from more than one input
+	SourceFormats        map[string]string // The format each root file was loaded in, keyed by file
+	CompiledApps         map[string]bool   // The apps loaded from the roots that are compiled modules
 	ProjectTitle         string
 	ImageDest            string // Output all images into this folder is set
 	Format               string // "html" or "markdown" or "" if custom
@@ -42,7 +48,8 @@ type Generator struct {
 	Templates            []*template.Template
 	Redoc                *template.Template
 	StartTemplateIndex   int
-	FilterPackage        []string // Filter these regex terms out of packagenames
+	FilterPackage        []*regexp.Regexp // Filter these regex terms out of packagenames
+	MetadataKeys         []string         // Attributes printed by ServiceMetadata; DefaultMetadataKeys if empty
 
 	Retriever      gop.Retriever
 	CustomTemplate bool
@@ -62,19 +69,58 @@ type Generator struct {
 	Links      map[string]string
 	Server     bool
 
-	Mapper *syslwrapper.AppMapper
+	Mapper   *syslwrapper.AppMapper
+	TypeDocs map[string]typeDoc   // Where each type is documented, keyed by "App.Type"
+	TypeUses map[string][]typeUse // Where each type is used, keyed by "App.Type"
 
 	BasePath string // for using on another endpoint that isn't '/'
+	BaseURL  string // The url the output is published at, used for links in diagrams rendered elsewhere
+
+	MermaidService string // Renders mermaid diagrams to SVG for Confluence pages, which can't run mermaid
+
+	SourceURLs  map[string]*template.Template // Templates of links to source files, keyed by file prefix
+	SourceFs    afero.Fs                      // Where sysl files are read from for their source pages
+	SourcePages map[string]string             // The source page of each sysl file, relative to OutputDir
+
+	Theme       catalogdiagrams.Theme // Style of the diagrams
+	StatsCharts bool                  // Add mermaid charts to the statistics page
+	OwnerKeys   []string              // Attributes that name the owner of an app; DefaultOwnerKeys if empty
+	CodeOwners  bool                  // Write a CODEOWNERS mapping of the sysl files to owners
+	JSONSchema  string                // Write JSON Schemas per app (JSONSchemaApps) or per type too (JSONSchemaTypes)
+
+	Lazy  bool       // Render the pages of projects and packages when they're first requested in server mode
+	cache *pageCache // The pages rendered since the module was updated, and the lock of the server
+
+	indexed bool // Whether the RootModule has been indexed since it was updated
 }
 
-// SourcePath appends CurrentDir to output
+// ServiceMetadata prints the MetadataKeys attributes of a.
+func (p *Generator) ServiceMetadata(a Attr) string {
+	if len(p.MetadataKeys) == 0 {
+		return ServiceMetadata(a)
+	}
+	return ServiceMetadataKeys(a, p.MetadataKeys...)
+}
+
+// SourcePath returns the link to the source of a, from the source url template configured for its file, to
+// its line on the source page of the file, or the path of the file (and its line when serving) otherwise.
+// The "source_path" attribute overrides the file.
 func (p *Generator) SourcePath(a SourceCoder) string {
-	//FIXME: handle source_path attr
-	// if source_path := Attribute(a, "source_path"); source_path != "" {
-	// 	return rootDirectory(path.Join(p.OutputDir, p.CurrentDir)) + Attribute(a, "source_path")
-	// }
-	// sourcePath, err := handleSourceURL(a.GetSourceContext().File)
-	str := BuildSpecURL(a.GetSourceContext().GetFile(), a.GetSourceContext().GetVersion())
+	ctx := a.GetSourceContext()
+	file := ctx.GetFile()
+	if sourcePath := Attribute(a, "source_path"); sourcePath != "" {
+		file = sourcePath
+	}
+	if url, ok := p.sourceURL(file, ctx); ok {
+		return url
+	}
+	if page, ok := p.SourcePages[file]; ok {
+		return p.relativeLink(page, strings.TrimPrefix(sourceLine(ctx), "#"))
+	}
+	str := BuildSpecURL(file, ctx.GetVersion())
+	if p.Server {
+		str += sourceLine(ctx)
+	}
 	return str
 }
 
@@ -186,6 +232,13 @@ func (p *Generator) WithTemplateFs(fs afero.Fs, fileNames ...string) *Generator
 	return p.WithTemplateString(tmpls...)
 }
 
+// WithConfig sets the options that are only available through a project configuration file.
+func (p *Generator) WithConfig(filterPackage []*regexp.Regexp, metadataKeys []string) *Generator {
+	p.FilterPackage = filterPackage
+	p.MetadataKeys = metadataKeys
+	return p
+}
+
 func (p *Generator) SetOptions(
 	disableCss bool,
 	readmeName, ImageDest string) *Generator {
@@ -199,30 +252,81 @@ func (p *Generator) SetOptions(
 
 // Run Executes a project and generates markdown and diagrams to a given filesystem.
 func (p *Generator) Run() {
+	if p.Format == "document" {
+		if err := p.RunDocument(); err != nil {
+			p.Log.Error("Error creating document:", err)
+		}
+		return
+	}
+	if p.Format == "confluence" {
+		if err := p.RunConfluence(); err != nil {
+			p.Log.Error("Error creating confluence pages:", err)
+		}
+		return
+	}
 	p.Title = p.ProjectTitle
 	fileName := markdownName(p.OutputFileName, path.Base(p.ProjectTitle))
 	p.Module = p.RootModule
 	if p.Module != nil {
-		p.Mapper = syslwrapper.MakeAppMapper(p.Module)
-		p.Mapper.IndexTypes()
-		p.Mapper.ConvertTypes()
+		p.indexModule()
+		if p.JSONSchema != "" {
+			if err := p.CreateJSONSchemas(); err != nil {
+				p.Log.Error("Error creating JSON Schemas:", err)
+			}
+		}
+		p.CreateSourcePages()
+		if !p.CustomTemplate {
+			p.Links = map[string]string{}
+			if page := p.CreateStatsPage(); page != "" {
+				p.Links["Statistics"] = page
+			}
+			if page := p.CreateOwnerPages(); page != "" {
+				p.Links["Owners"] = page
+			}
+			if page := p.CreateDeprecationReport(); page != "" {
+				p.Links["Deprecations"] = page
+			}
+			if page := p.CreateUnusedTypesPage(); page != "" {
+				p.Links["Unused Types"] = page
+			}
+		}
+		if p.CodeOwners {
+			if err := p.CreateCodeOwners(); err != nil {
+				p.Log.Error("Error creating "+CodeOwnersFile+":", err)
+			}
+		}
 	}
 	if err := p.CreateMarkdown(p.Templates[p.StartTemplateIndex], path.Join(p.OutputDir, fileName), p); err != nil {
 		p.Log.Error("Error creating project markdown:", err)
 	}
 }
 
+// indexModule maps the types of the root module, and where each of them is documented and used, unless it's
+// been indexed since it was updated.
+func (p *Generator) indexModule() {
+	if p.indexed {
+		return
+	}
+	p.indexed = true
+	p.Mapper = syslwrapper.MakeAppMapper(p.RootModule)
+	p.Mapper.IndexTypes()
+	p.Mapper.ConvertTypes()
+	p.TypeDocs = p.typeDocs()
+	p.TypeUses = p.typeUses()
+}
+
 // GetFuncMap returns the funcs that are used in diagram generation.
 func (p *Generator) GetFuncMap() template.FuncMap {
 	f := template.FuncMap{
 
 		/* Mermaid Diagram functions */
-		"IntegrationMermaid":     p.IntegrationMermaid,
-		"SequenceMermaid":        p.SequenceMermaid,
-		"DataModelReturnMermaid": p.DataModelReturnMermaid,
-		"DataModelMermaid":       p.DataModelMermaid,
-		"DataModelAliasMermaid":  p.DataModelAliasMermaid,
-		"DataModelAppMermaid":    p.DataModelAppMermaid,
+		"IntegrationMermaid":      p.IntegrationMermaid,
+		"IntegrationMermaidViews": p.IntegrationMermaidViews,
+		"SequenceMermaid":         p.SequenceMermaid,
+		"DataModelReturnMermaid":  p.DataModelReturnMermaid,
+		"DataModelMermaid":        p.DataModelMermaid,
+		"DataModelAliasMermaid":   p.DataModelAliasMermaid,
+		"DataModelAppMermaid":     p.DataModelAppMermaid,
 
 		// Datamodel table functions
 		"DataModelReturnTable": p.DataModelReturnTable,
@@ -231,13 +335,28 @@ func (p *Generator) GetFuncMap() template.FuncMap {
 
 		/* Plantuml Diagram functions */
 
-		"IntegrationPlantuml":     p.IntegrationPlantuml,
-		"SequencePlantuml":        p.SequencePlantuml,
-		"DataModelReturnPlantuml": p.DataModelReturnPlantuml,
-		"DataModelParamPlantuml":  p.DataModelParamPlantuml,
-		"DataModelAppPlantuml":    p.DataModelAppPlantuml,
-		"DataModelPlantuml":       p.DataModelPlantuml,
-		"DataModelAliasPlantuml":  p.DataModelAliasPlantuml,
+		"IntegrationPlantuml":      p.IntegrationPlantuml,
+		"IntegrationPlantumlViews": p.IntegrationPlantumlViews,
+		"SequencePlantuml":         p.SequencePlantuml,
+		"DataModelReturnPlantuml":  p.DataModelReturnPlantuml,
+		"DataModelParamPlantuml":   p.DataModelParamPlantuml,
+		"DataModelAppPlantuml":     p.DataModelAppPlantuml,
+		"DataModelPlantuml":        p.DataModelPlantuml,
+		"DataModelAliasPlantuml":   p.DataModelAliasPlantuml,
+
+		/* C4 functions */
+		"C4ContextPlantuml":   p.C4ContextPlantuml,
+		"C4ContainerPlantuml": p.C4ContainerPlantuml,
+		"C4ContextMermaid":    p.C4ContextMermaid,
+		"C4ContainerMermaid":  p.C4ContainerMermaid,
+
+		/* Pub/sub functions */
+		"Events":            p.Events,
+		"IsEvent":           IsEvent,
+		"IsSubscriber":      IsSubscriber,
+		"IsPubSub":          IsPubSub,
+		"EventFlowMermaid":  p.EventFlowMermaid,
+		"EventFlowPlantuml": p.EventFlowPlantuml,
 
 		/* Redoc Functions */
 		"CreateRedoc": p.CreateRedoc,
@@ -248,15 +367,28 @@ func (p *Generator) GetFuncMap() template.FuncMap {
 		"SourcePath":         p.SourcePath,
 		"Packages":           p.Packages,
 		"MacroPackages":      p.MacroPackages,
+		"RootProjects":       p.RootProjects,
+		"Stats":              p.Stats,
+		"OwnerOf":            p.OwnerOf,
+		"Deprecated":         Deprecated,
+		"DeprecationBadge":   DeprecationBadge,
+		"ExampleJSON":        p.ExampleJSON,
+		"ExampleParamJSON":   p.ExampleParamJSON,
+		"ExampleReturnJSON":  p.ExampleReturnJSON,
+		"CodeBlock":          CodeBlock,
+		"JSONSchemaLink":     p.JSONSchemaLink,
+		"UsedBy":             p.UsedBy,
+		"UnusedTypes":        p.UnusedTypes,
 		"hasPattern":         syslutil.HasPattern,
 		"ModuleAsPackages":   p.ModuleAsPackages,
 		"ModulePackageName":  ModulePackageName,
 		"ModuleNamespace":    ModuleNamespace,
 		"SortedKeys":         SortedKeys,
 		"Attribute":          Attribute,
-		"ServiceMetadata":    ServiceMetadata,
+		"ServiceMetadata":    p.ServiceMetadata,
 		"Fields":             Fields,
 		"FieldType":          FieldType,
+		"FieldTypeLink":      p.FieldTypeLink,
 		"SanitiseOutputName": SanitiseOutputName,
 		"SimpleName":         SimpleName,
 		"ToLower":            strings.ToLower,
@@ -284,6 +416,15 @@ func Last(i interface{}, ind int) bool {
 	return ind == len(SortedKeys(i))-1
 }
 
+// filteredPackageName returns the package of app with the FilterPackage terms removed.
+func (p *Generator) filteredPackageName(app Namer) string {
+	s := GetPackageName(p.RootModule, app)
+	for _, re := range p.FilterPackage {
+		s = re.ReplaceAllString(s, "")
+	}
+	return s
+}
+
 func Remove(s string, old ...string) string {
 	for _, e := range old {
 		re := regexp.MustCompile(e)
@@ -0,0 +1,215 @@
+// integration_views.go: integration diagrams that are split into clusters when they get too large to read
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+)
+
+// plantumlMaxURL is the longest diagram url that PlantUML servers reliably accept.
+const plantumlMaxURL = 8000
+
+// IntegrationView is one diagram of an integration diagram that may have been split up.
+// Diagram is empty when the apps are listed in Dependencies instead.
+type IntegrationView struct {
+	Title        string
+	Diagram      string // A plantuml url or mermaid source
+	Dependencies []Dependency
+}
+
+// Dependency is a call from one app (or cluster of apps) to another.
+type Dependency struct {
+	From string
+	To   string
+}
+
+// dependencies returns the apps that the apps of m call or are called by, sorted by caller and callee.
+func (p *Generator) dependencies(m *sysl.Module) (nodes []string, deps []Dependency) {
+	seenNodes := make(map[string]bool)
+	seenDeps := make(map[Dependency]bool)
+	for _, appName := range SortedKeys(p.RootModule.GetApps()) {
+		app := p.RootModule.GetApps()[appName]
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			forEachCall(app.GetEndpoints()[endpointName].GetStmt(), func(call *sysl.Call) {
+				target := JoinAppNameString(call.GetTarget())
+				_, fromM := m.GetApps()[appName]
+				_, toM := m.GetApps()[target]
+				if target == appName || !(fromM || toM) {
+					return
+				}
+				dep := Dependency{From: appName, To: target}
+				if seenDeps[dep] {
+					return
+				}
+				seenDeps[dep] = true
+				deps = append(deps, dep)
+				for _, n := range []string{appName, target} {
+					if !seenNodes[n] {
+						seenNodes[n] = true
+						nodes = append(nodes, n)
+					}
+				}
+			})
+		}
+	}
+	for _, appName := range SortedKeys(m.GetApps()) {
+		if !seenNodes[appName] {
+			nodes = append(nodes, appName)
+		}
+	}
+	return nodes, deps
+}
+
+// clusters groups apps by package, or by namespace when they're all in the same package, and returns the
+// sorted cluster names.
+// Less than two clusters are returned when the apps can't be split.
+func (p *Generator) clusters(apps []string) (clusterOf map[string]string, names []string) {
+	byPackage := make(map[string]string, len(apps))
+	byNamespace := make(map[string]string, len(apps))
+	for _, appName := range apps {
+		if app, ok := p.RootModule.GetApps()[appName]; ok {
+			byPackage[appName] = p.filteredPackageName(app)
+		} else {
+			byPackage[appName] = appName
+		}
+		byNamespace[appName] = appName
+		if i := strings.LastIndex(appName, namespaceSeparator); i >= 0 {
+			byNamespace[appName] = appName[:i]
+		}
+	}
+	for _, clusterOf := range []map[string]string{byPackage, byNamespace} {
+		seen := make(map[string]bool)
+		names = nil
+		for _, appName := range apps {
+			if c := clusterOf[appName]; !seen[c] {
+				seen[c] = true
+				names = append(names, c)
+			}
+		}
+		if len(names) > 1 {
+			sort.Strings(names)
+			return clusterOf, names
+		}
+	}
+	return nil, nil
+}
+
+// tooLarge returns true when a diagram of nodes and deps exceeds maxNodes or maxEdges (0 is unlimited).
+func tooLarge(nodes []string, deps []Dependency, maxNodes, maxEdges int) bool {
+	return maxNodes > 0 && len(nodes) > maxNodes || maxEdges > 0 && len(deps) > maxEdges
+}
+
+// integrationViews returns a single integration diagram of m when it's small enough to read, otherwise
+// a summary of the clusters of apps in m and a diagram (or a list of dependencies) for each cluster. diagram
+// and summary return "" for diagrams that are too large to draw, which are listed as dependencies instead.
+func (p *Generator) integrationViews(
+	m *sysl.Module, title string, maxNodes, maxEdges int,
+	diagram func(m *sysl.Module, title string) string,
+	summary func(nodes []string, deps []Dependency) string,
+) []IntegrationView {
+	nodes, deps := p.dependencies(m)
+	if !tooLarge(nodes, deps, maxNodes, maxEdges) {
+		if d := diagram(m, title); d != "" || len(deps) == 0 {
+			return []IntegrationView{{Diagram: d}}
+		}
+	}
+	clusterOf, clusterNames := p.clusters(nodes)
+	var clusterDeps []Dependency
+	seen := make(map[Dependency]bool)
+	for _, dep := range deps {
+		c := Dependency{From: clusterOf[dep.From], To: clusterOf[dep.To]}
+		if c.From != c.To && !seen[c] {
+			seen[c] = true
+			clusterDeps = append(clusterDeps, c)
+		}
+	}
+	if len(clusterNames) < 2 || tooLarge(clusterNames, clusterDeps, maxNodes, maxEdges) {
+		return []IntegrationView{{Title: title, Dependencies: deps}}
+	}
+	views := []IntegrationView{{Title: title, Diagram: summary(clusterNames, clusterDeps)}}
+	if views[0].Diagram == "" {
+		views[0].Dependencies = clusterDeps // the summary is too large to draw too
+	}
+	for _, clusterName := range clusterNames {
+		cluster := &sysl.Module{Apps: map[string]*sysl.Application{}}
+		for appName, app := range m.GetApps() {
+			if clusterOf[appName] == clusterName {
+				cluster.Apps[appName] = app
+			}
+		}
+		if len(cluster.Apps) == 0 {
+			continue // only called by (or calling) the apps of m, so it's in the summary
+		}
+		clusterTitle := title + namespaceSeparator + clusterName
+		nodes, deps := p.dependencies(cluster)
+		if !tooLarge(nodes, deps, maxNodes, maxEdges) {
+			if d := diagram(cluster, clusterTitle); d != "" {
+				views = append(views, IntegrationView{Title: clusterTitle, Diagram: d})
+				continue
+			}
+		}
+		views = append(views, IntegrationView{Title: clusterTitle, Dependencies: deps})
+	}
+	return views
+}
+
+// IntegrationPlantumlViews returns the integration diagram of m as plantuml urls, split into a summary and
+// a diagram per cluster of apps when there are more than maxNodes apps or maxEdges dependencies.
+func (p *Generator) IntegrationPlantumlViews(m *sysl.Module, title string, EPA bool, maxNodes, maxEdges int) []IntegrationView {
+	return p.integrationViews(m, title, maxNodes, maxEdges,
+		func(m *sysl.Module, title string) string {
+			url, err := p.IntegrationPlantuml(m, title, EPA)
+			if err != nil {
+				p.Log.Error(err)
+				return ""
+			}
+			if len(url) > plantumlMaxURL {
+				return ""
+			}
+			return url
+		},
+		func(nodes []string, deps []Dependency) string {
+			ids := make(map[string]string, len(nodes))
+			var b strings.Builder
+			b.WriteString("@startuml\n")
+			for i, n := range nodes {
+				ids[n] = fmt.Sprintf("_%d", i)
+				fmt.Fprintf(&b, "folder \"%s\" as %s\n", n, ids[n])
+			}
+			for _, dep := range deps {
+				fmt.Fprintf(&b, "%s --> %s\n", ids[dep.From], ids[dep.To])
+			}
+			b.WriteString("@enduml\n")
+			if url := p.plantumlURL(b.String()); len(url) <= plantumlMaxURL {
+				return url
+			}
+			return ""
+		},
+	)
+}
+
+// IntegrationMermaidViews returns the integration diagram of m as mermaid diagrams, split into a summary and
+// a diagram per cluster of apps when there are more than maxNodes apps or maxEdges dependencies.
+func (p *Generator) IntegrationMermaidViews(m *sysl.Module, title string, EPA bool, maxNodes, maxEdges int) []IntegrationView {
+	return p.integrationViews(m, title, maxNodes, maxEdges,
+		func(m *sysl.Module, title string) string {
+			return p.IntegrationMermaid(m, title, EPA)
+		},
+		func(nodes []string, deps []Dependency) string {
+			ids := make(map[string]string, len(nodes))
+			var b strings.Builder
+			b.WriteString("graph TD\n")
+			for i, n := range nodes {
+				ids[n] = fmt.Sprintf("c%d", i)
+				fmt.Fprintf(&b, "    %s[\"%s\"]\n", ids[n], n)
+			}
+			for _, dep := range deps {
+				fmt.Fprintf(&b, "    %s --> %s\n", ids[dep.From], ids[dep.To])
+			}
+			return p.themeMermaid(b.String())
+		},
+	)
+}
@@ -0,0 +1,56 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrationViews(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	p := NewProject("test", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	views := p.IntegrationMermaidViews(m, "test", false, 0, 0)
+	require.Len(t, views, 1)
+	assert.Equal(t, "", views[0].Title)
+	assert.NotEmpty(t, views[0].Diagram)
+
+	// Three apps are split into the Orders and Fulfilment packages.
+	views = p.IntegrationMermaidViews(m, "test", false, 2, 0)
+	require.Len(t, views, 3)
+	assert.Equal(t, "test", views[0].Title)
+	assert.Contains(t, views[0].Diagram, `["Fulfilment"]`)
+	assert.Equal(t, "test :: Fulfilment", views[1].Title)
+	assert.Equal(t, "test :: Orders", views[2].Title)
+
+	// Too large to split into clusters of one app.
+	views = p.IntegrationMermaidViews(m, "test", false, 1, 0)
+	require.Len(t, views, 1)
+	assert.Empty(t, views[0].Diagram)
+	assert.Contains(t, views[0].Dependencies, Dependency{From: "Shipping", To: "Warehouse"})
+}
+
+func TestIntegrationViewsSummaryTooLarge(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	p := NewProject("test", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	// A summary whose url would be too long is listed as the dependencies of the clusters.
+	views := p.integrationViews(m, "test", 2, 0,
+		func(m *sysl.Module, title string) string { return "diagram" },
+		func(nodes []string, deps []Dependency) string { return "" },
+	)
+	require.Len(t, views, 3)
+	assert.Empty(t, views[0].Diagram)
+	assert.Contains(t, views[0].Dependencies, Dependency{From: "Orders", To: "Fulfilment"})
+}
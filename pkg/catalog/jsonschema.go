@@ -0,0 +1,258 @@
+// jsonschema.go: JSON Schema (draft 2020-12) documents of the types of each app, for contract testing
+package catalog
+
+import (
+	"encoding/json"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslwrapper"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+const (
+	schemasDir      = "schemas" // The directory of the JSON Schemas in the output directory
+	JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+	JSONSchemaApps  = "app"  // Write a JSON Schema per app, with a definition per type
+	JSONSchemaTypes = "type" // Also write a JSON Schema per type
+)
+
+// WithJSONSchema writes JSON Schemas of the types of each app (JSONSchemaApps), and of each type
+// (JSONSchemaTypes), or none if mode is "".
+func (p *Generator) WithJSONSchema(mode string) *Generator {
+	p.JSONSchema = mode
+	return p
+}
+
+// schemaName returns the name of an app (or type) in the file names of JSON Schemas.
+func schemaName(name string) string {
+	return SanitiseOutputName(strings.ReplaceAll(name, " :: ", "_"))
+}
+
+// schemaFileName returns the file name of the JSON Schema of an app (or type).
+func schemaFileName(name string) string {
+	return schemaName(name) + ".schema.json"
+}
+
+// splitReference splits a type reference ("App.Type") into its app and type names.
+func splitReference(reference string) (appName, typeName string) {
+	i := strings.LastIndex(reference, ".")
+	if i < 0 {
+		return "", reference
+	}
+	return reference[:i], reference[i+1:]
+}
+
+// schemaTypes returns the names of the types of each app that has any, from the simple types of the mapper.
+func (p *Generator) schemaTypes() map[string][]string {
+	types := make(map[string][]string)
+	if p.Mapper == nil {
+		return types
+	}
+	for _, reference := range SortedKeys(p.Mapper.SimpleTypes) {
+		appName, typeName := splitReference(reference)
+		types[appName] = append(types[appName], typeName)
+	}
+	return types
+}
+
+// JSONSchemaLink returns a link to the JSON Schema of a type, or "" if JSON Schemas aren't written.
+func (p *Generator) JSONSchemaLink(appName, typeName string) string {
+	if p.JSONSchema == "" || p.Mapper == nil {
+		return ""
+	}
+	if _, ok := p.Mapper.SimpleTypes[appName+"."+typeName]; !ok {
+		return ""
+	}
+	if p.JSONSchema == JSONSchemaTypes {
+		return p.pageLink(path.Join(schemasDir, schemaName(appName), schemaFileName(typeName)), "")
+	}
+	return p.pageLink(path.Join(schemasDir, schemaFileName(appName)), "/$defs/"+typeName)
+}
+
+// AppJSONSchema returns the JSON Schema of the types of an app, which refers to the schemas of other apps
+// in the same directory.
+func (p *Generator) AppJSONSchema(appName string) map[string]interface{} {
+	ref := func(reference string) string {
+		refAppName, typeName := splitReference(reference)
+		if refAppName == appName {
+			return "#/$defs/" + typeName
+		}
+		return schemaFileName(refAppName) + "#/$defs/" + typeName
+	}
+	defs := make(map[string]interface{})
+	for _, typeName := range p.schemaTypes()[appName] {
+		defs[typeName] = p.typeSchema(p.Mapper.SimpleTypes[appName+"."+typeName],
+			p.RootModule.GetApps()[appName].GetTypes()[typeName], ref)
+	}
+	schema := map[string]interface{}{
+		"$schema": JSONSchemaDraft,
+		"title":   appName,
+		"$defs":   defs,
+	}
+	if description := Attribute(p.RootModule.GetApps()[appName], "description"); description != "" {
+		schema["description"] = description
+	}
+	return schema
+}
+
+// TypeJSONSchema returns the JSON Schema of a type, which refers to the schemas of the apps in the parent
+// directory.
+func (p *Generator) TypeJSONSchema(appName, typeName string) map[string]interface{} {
+	ref := func(reference string) string {
+		refAppName, refTypeName := splitReference(reference)
+		return "../" + schemaFileName(refAppName) + "#/$defs/" + refTypeName
+	}
+	schema := p.typeSchema(p.Mapper.SimpleTypes[appName+"."+typeName],
+		p.RootModule.GetApps()[appName].GetTypes()[typeName], ref)
+	schema["$schema"] = JSONSchemaDraft
+	schema["title"] = appName + "." + typeName
+	return schema
+}
+
+// primitiveSchema returns the JSON Schema of a primitive type (in lower case).
+func primitiveSchema(typeName string) map[string]interface{} {
+	switch typeName {
+	case "bool":
+		return map[string]interface{}{"type": "boolean"}
+	case "int", "int32", "int64":
+		return map[string]interface{}{"type": "integer"}
+	case "float", "float32", "float64", "double", "decimal":
+		return map[string]interface{}{"type": "number"}
+	case "bytes":
+		return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+	case "date":
+		return map[string]interface{}{"type": "string", "format": "date"}
+	case "datetime":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "uuid":
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	case "any", "":
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{"type": "string"}
+}
+
+// typeSchema returns the JSON Schema of a simple type, with the constraints and attributes of the sysl type
+// it's made from (nil if unknown). ref returns the $ref of a type reference.
+func (p *Generator) typeSchema(
+	t *syslwrapper.Type, syslType *sysl.Type, ref func(reference string) string,
+) map[string]interface{} {
+	var schema map[string]interface{}
+	switch t.Type {
+	case "ref":
+		schema = map[string]interface{}{"$ref": ref(t.Reference)}
+	case "list":
+		schema = map[string]interface{}{"type": "array"}
+		if len(t.Items) > 0 {
+			schema["items"] = p.typeSchema(t.Items[0], syslType.GetSequence(), ref)
+		}
+	case "map":
+		schema = map[string]interface{}{"type": "object"}
+		if len(t.Items) > 1 {
+			schema["additionalProperties"] = p.typeSchema(t.Items[1], nil, ref)
+		}
+	case "enum":
+		var keys []int64
+		for k := range t.Enum {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		var names []string
+		for _, k := range keys {
+			names = append(names, t.Enum[k])
+		}
+		schema = map[string]interface{}{"type": "string", "enum": names}
+	case "tuple", "relation":
+		fields := typeFields(syslType)
+		properties := make(map[string]interface{}, len(t.Properties))
+		var required []string
+		for _, name := range SortedKeys(t.Properties) {
+			field := t.Properties[name]
+			properties[name] = p.typeSchema(field, fields[name], ref)
+			if !field.Optional {
+				required = append(required, name)
+			}
+		}
+		schema = map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	default:
+		schema = primitiveSchema(strings.ToLower(t.Type))
+		constrainSchema(schema, syslType.GetConstraint())
+	}
+	if description := Attribute(syslType, "description"); description != "" {
+		schema["description"] = description
+	} else if t.Description != "" {
+		schema["description"] = t.Description
+	}
+	if syslType != nil && Deprecated(syslType) {
+		schema["deprecated"] = true
+	}
+	return schema
+}
+
+// constrainSchema adds the length, range and scale constraints of a primitive type to its schema.
+func constrainSchema(schema map[string]interface{}, constraints []*sysl.Type_Constraint) {
+	for _, c := range constraints {
+		if length := c.GetLength(); length != nil && schema["type"] == "string" {
+			if length.GetMin() > 0 {
+				schema["minLength"] = length.GetMin()
+			}
+			if length.GetMax() > 0 {
+				schema["maxLength"] = length.GetMax()
+			}
+		}
+		if r := c.GetRange(); r != nil {
+			for key, v := range map[string]*sysl.Value{"minimum": r.GetMin(), "maximum": r.GetMax()} {
+				switch v.GetValue().(type) {
+				case *sysl.Value_I:
+					schema[key] = v.GetI()
+				case *sysl.Value_D:
+					schema[key] = v.GetD()
+				}
+			}
+		}
+		if c.GetScale() > 0 && schema["type"] == "number" {
+			schema["multipleOf"] = math.Pow10(-int(c.GetScale()))
+		}
+	}
+}
+
+// CreateJSONSchemas writes the JSON Schema of each app to schemas/, and of each type to schemas/<app>/ if
+// the mode is JSONSchemaTypes.
+func (p *Generator) CreateJSONSchemas() error {
+	write := func(filename string, schema map[string]interface{}) error {
+		b, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, filename)
+		}
+		if err := p.Fs.MkdirAll(path.Dir(filename), 0755); err != nil {
+			return err
+		}
+		return afero.WriteFile(p.Fs, filename, append(b, '\n'), 0644)
+	}
+	types := p.schemaTypes()
+	for _, appName := range SortedKeys(types) {
+		dir := path.Join(p.OutputDir, schemasDir)
+		if err := write(path.Join(dir, schemaFileName(appName)), p.AppJSONSchema(appName)); err != nil {
+			return err
+		}
+		if p.JSONSchema != JSONSchemaTypes {
+			continue
+		}
+		for _, typeName := range types[appName] {
+			filename := path.Join(dir, schemaName(appName), schemaFileName(typeName))
+			if err := write(filename, p.TypeJSONSchema(appName, typeName)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package catalog
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jsonSchemaSysl = `
+Orders:
+    @package = "Orders"
+    PlaceOrder(order <: Order): ...
+    !type Order:
+        id <: int
+        reference <: string(10)
+        total <: decimal(10.2)
+        note <: string?
+        status <: Status
+        lines <: sequence of Line
+        customer <: Customers.Customer
+    !type Line:
+        sku <: string:
+            @description = "Stock keeping unit"
+    !enum Status:
+        open: 1
+        closed: 2
+Customers:
+    @package = "Customers"
+    !type Customer:
+        name <: string
+`
+
+func readSchema(t *testing.T, fs afero.Fs, filename string) map[string]interface{} {
+	b, err := afero.ReadFile(fs, filename)
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &schema))
+	return schema
+}
+
+func TestJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(jsonSchemaSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out").
+		WithJSONSchema(JSONSchemaApps).Run()
+
+	schema := readSchema(t, fs, "out/schemas/Orders.schema.json")
+	assert.Equal(t, JSONSchemaDraft, schema["$schema"])
+	defs := schema["$defs"].(map[string]interface{})
+	order := defs["Order"].(map[string]interface{})
+	assert.Equal(t, "object", order["type"])
+	assert.ElementsMatch(t, []interface{}{"id", "reference", "total", "status", "lines", "customer"}, order["required"])
+	properties := order["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "integer"}, properties["id"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "maxLength": 10.0}, properties["reference"])
+	assert.Equal(t, map[string]interface{}{"type": "number", "multipleOf": 0.01}, properties["total"])
+	assert.Equal(t, map[string]interface{}{"$ref": "#/$defs/Status"}, properties["status"])
+	assert.Equal(t, map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/$defs/Line"}},
+		properties["lines"])
+	assert.Equal(t, map[string]interface{}{"$ref": "Customers.schema.json#/$defs/Customer"}, properties["customer"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "enum": []interface{}{"open", "closed"}}, defs["Status"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "description": "Stock keeping unit"},
+		defs["Line"].(map[string]interface{})["properties"].(map[string]interface{})["sku"])
+	readSchema(t, fs, "out/schemas/Customers.schema.json")
+	_, err = fs.Stat("out/schemas/Orders/Order.schema.json")
+	assert.Error(t, err)
+
+	b, err := afero.ReadFile(fs, "out/Orders/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "[JSON Schema](../schemas/Orders.schema.json#/$defs/Order)")
+}
+
+func TestJSONSchemaPerType(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(jsonSchemaSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out").
+		WithJSONSchema(JSONSchemaTypes).Run()
+
+	schema := readSchema(t, fs, "out/schemas/Orders/Order.schema.json")
+	assert.Equal(t, "Orders.Order", schema["title"])
+	properties := schema["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$ref": "../Orders.schema.json#/$defs/Status"}, properties["status"])
+	assert.Equal(t, map[string]interface{}{"$ref": "../Customers.schema.json#/$defs/Customer"}, properties["customer"])
+
+	b, err := afero.ReadFile(fs, "out/Orders/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "[JSON Schema](../schemas/Orders/Order.schema.json)")
+}
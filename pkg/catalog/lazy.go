@@ -0,0 +1,143 @@
+// lazy.go: rendering the pages of projects and packages when they're first requested in server mode
+package catalog
+
+import (
+	"path"
+	"sync"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+)
+
+// pageCache is the pages rendered since the module was last updated, keyed by page (relative to OutputDir),
+// where "" is the project page and the pages it creates (statistics, owners, sources...).
+type pageCache struct {
+	mu       sync.RWMutex // Read locked while serving, and locked while updating and rendering, as rendering changes the state of the Generator
+	rendered map[string]bool
+	pages    map[string]lazyPage // The lazyPages of the module
+
+	rendersMu sync.Mutex
+	renders   map[string]*sync.Mutex // Held while a page is rendered, so the requests for it wait for one render
+}
+
+// lazyPage is a MacroPackage or package page that's rendered when it's requested.
+type lazyPage struct {
+	MacroPackage string
+	Package      string // "" for the page of the MacroPackage
+	Module       *sysl.Module
+}
+
+// WithLazyRendering renders the pages of projects and packages when they're first requested instead of when
+// the module is updated, so large modules can be browsed sooner. Rendered pages are kept until the next update.
+// Custom templates are always rendered on update.
+func (p *Generator) WithLazyRendering(lazy bool) *Generator {
+	p.Lazy = lazy
+	return p
+}
+
+// lazy returns whether pages are rendered when they're requested.
+func (p *Generator) lazy() bool {
+	return p.Lazy && !p.CustomTemplate && p.cache != nil
+}
+
+// lock locks the server, and returns the func that unlocks it.
+func (p *Generator) lock() func() {
+	if p.cache == nil {
+		return func() {}
+	}
+	p.cache.mu.Lock()
+	return p.cache.mu.Unlock
+}
+
+// rlock read locks the server, and returns the func that unlocks it.
+func (p *Generator) rlock() func() {
+	if p.cache == nil {
+		return func() {}
+	}
+	p.cache.mu.RLock()
+	return p.cache.mu.RUnlock
+}
+
+// lockPage locks the rendering of page, and returns the func that unlocks it.
+func (c *pageCache) lockPage(page string) func() {
+	c.rendersMu.Lock()
+	if c.renders == nil {
+		c.renders = make(map[string]*sync.Mutex)
+	}
+	mu, ok := c.renders[page]
+	if !ok {
+		mu = &sync.Mutex{}
+		c.renders[page] = mu
+	}
+	c.rendersMu.Unlock()
+	mu.Lock()
+	return mu.Unlock
+}
+
+// lazyPages returns the MacroPackage and package pages of the root module, keyed by page (relative to
+// OutputDir). The pages are computed the same way as MacroPackages and Packages lay them out.
+func (p *Generator) lazyPages() map[string]lazyPage {
+	pages := make(map[string]lazyPage)
+	macroPackages := map[string]*sysl.Module{"": p.RootModule}
+	if p.StartTemplateIndex == 0 {
+		macroPackages = p.ModuleAsMacroPackage(p.RootModule)
+	}
+	for macroPackageName, macroPackage := range macroPackages {
+		if macroPackageName != "" {
+			page := path.Join(macroPackageName, markdownName(p.OutputFileName, macroPackageName))
+			pages[page] = lazyPage{MacroPackage: macroPackageName, Module: macroPackage}
+		}
+		for packageName, pkg := range p.ModuleAsPackages(macroPackage) {
+			page := path.Join(macroPackageName, packageName, markdownName(p.OutputFileName, packageName))
+			pages[page] = lazyPage{MacroPackage: macroPackageName, Package: packageName, Module: pkg}
+		}
+	}
+	return pages
+}
+
+// renderPage renders a page (relative to OutputDir) unless it's been rendered since the last update. The
+// project page, and the pages it creates, are rendered first as the other pages link to them. It's called
+// without holding the lock of the server, so the pages that are already rendered are served in the meantime.
+func (p *Generator) renderPage(page string) {
+	p.renderOnce("", p.Run)
+	p.renderOnce(page, func() {
+		lp := p.cache.pages[page]
+		defer p.keepPage()()
+		if lp.Package == "" {
+			p.createMacroPackage(lp.MacroPackage, lp.Module)
+		} else {
+			p.TempDir = lp.MacroPackage
+			p.createPackage(lp.Package, lp.Module)
+		}
+	})
+}
+
+// renderOnce renders page if it needs rendering. Only one request renders a page, the others wait for it
+// under the lock of the page, and rendering locks the server.
+func (p *Generator) renderOnce(page string, render func()) {
+	needsRender := func() bool {
+		defer p.rlock()()
+		return p.needsRender(page)
+	}
+	if !needsRender() {
+		return
+	}
+	defer p.cache.lockPage(page)()
+	if !needsRender() {
+		return
+	}
+	defer p.lock()()
+	if p.needsRender(page) {
+		render()
+		p.cache.rendered[page] = true
+	}
+}
+
+// needsRender returns whether page is the project page or a lazyPage that hasn't been rendered since the last
+// update.
+func (p *Generator) needsRender(page string) bool {
+	if p.RootModule == nil || len(p.errs) > 0 || p.cache.rendered[page] {
+		return false
+	}
+	_, ok := p.cache.pages[page]
+	return page == "" || ok
+}
@@ -0,0 +1,106 @@
+package catalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyRendering(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(apiSysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), nil, nil, "").
+		ServerSettings(false, false, false).
+		WithLazyRendering(true).
+		Update(m)
+	exists := func(page string) bool {
+		ok, err := afero.Exists(p.Fs, page)
+		require.NoError(t, err)
+		return ok
+	}
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Body.String()
+	}
+	assert.False(t, exists("/README.md"))
+
+	assert.Contains(t, get("/Orders/README.md"), "# Orders")
+	assert.True(t, exists("/README.md"))
+	assert.True(t, exists("/Orders/README.md"))
+	assert.False(t, exists("/Customers/README.md"))
+
+	assert.Contains(t, get("/README.md"), "Customers/README.md")
+	assert.False(t, exists("/Customers/README.md"))
+	assert.Contains(t, get("/Customers/README.md"), "# Customers")
+
+	p.Update(m)
+	assert.False(t, exists("/Orders/README.md"))
+	assert.Contains(t, get("/Orders/README.md"), "# Orders")
+}
+
+func TestLazyRenderingConcurrently(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(apiSysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), nil, nil, "").
+		ServerSettings(false, false, false).
+		WithLazyRendering(true).
+		Update(m)
+	var wg sync.WaitGroup
+	for _, target := range []string{"/README.md", "/Orders/README.md", "/Customers/README.md", "/api/apps/Orders"} {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				rec := httptest.NewRecorder()
+				p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+				assert.Equal(t, http.StatusOK, rec.Code, target)
+			}
+		}(target)
+	}
+	for i := 0; i < 5; i++ {
+		p.Update(m)
+	}
+	wg.Wait()
+}
+
+func TestLazyRenderingReadLock(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(apiSysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), nil, nil, "").
+		ServerSettings(false, false, false).
+		WithLazyRendering(true).
+		Update(m)
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Body.String()
+	}
+	assert.Contains(t, get("/Orders/README.md"), "# Orders")
+
+	// A rendered page is served while another request holds the read lock.
+	unlock := p.rlock()
+	defer unlock()
+	served := make(chan string)
+	go func() { served <- get("/Orders/README.md") }()
+	select {
+	case page := <-served:
+		assert.Contains(t, page, "# Orders")
+	case <-time.After(10 * time.Second):
+		t.Fatal("the rendered page wasn't served")
+	}
+}
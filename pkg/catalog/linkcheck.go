@@ -0,0 +1,227 @@
+// linkcheck.go: checks that the relative links and anchors in generated output resolve
+package catalog
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+var (
+	checkedAnchor   = regexp.MustCompile(`<a name=(?:"([^"]*)"|(\S*?)>\s*</a>)|\sid="([^"]+)"`)
+	checkedHeading  = regexp.MustCompile(`(?m)^#{1,6} (.+)$`)
+	checkedMarkdown = regexp.MustCompile(`\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	checkedHTML     = regexp.MustCompile(`\s(?:href|src)="([^"]*)"`)
+	checkedClick    = regexp.MustCompile(`(?m)^\s*click \S+ "([^"]*)"`)
+	rowAnchor       = regexp.MustCompile(`(?:\]\(|href=")#([^)"\s]+)`)
+	githubSlugChars = regexp.MustCompile(`[^\w\- ]`)
+)
+
+// BrokenLink is a relative link (or anchor) in the generated output that doesn't resolve.
+type BrokenLink struct {
+	Page    string // The page the link is on, relative to the checked directory
+	Section string // The anchor of the section the link is in, which names the app (and endpoint or type)
+	Link    string
+	Reason  string
+}
+
+func (l BrokenLink) String() string {
+	page := l.Page
+	if l.Section != "" {
+		page += "#" + l.Section
+	}
+	return fmt.Sprintf("%s: %s: %s", page, l.Link, l.Reason)
+}
+
+// checkedPage is the anchors and links of a page, in the order they appear.
+type checkedPage struct {
+	anchors map[string]int // The number of times each anchor is defined
+	refs    []pageRef
+}
+
+// pageRef is a link on a page and the section it's in.
+type pageRef struct {
+	link    string
+	section string
+}
+
+// isPage returns true for the files whose links are checked.
+func isPage(filename string) bool {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".md", ".markdown", ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// githubSlug returns the anchor GitHub generates for a markdown heading.
+func githubSlug(heading string) string {
+	heading = htmlTag.ReplaceAllString(heading, "")
+	return strings.ReplaceAll(strings.ToLower(githubSlugChars.ReplaceAllString(strings.TrimSpace(heading), "")), " ", "-")
+}
+
+// readCheckedPage finds the anchors and links of a page.
+func readCheckedPage(contents string, markdown bool) checkedPage {
+	page := checkedPage{anchors: make(map[string]int)}
+	type anchorAt struct {
+		name string
+		at   int
+	}
+	var sections []anchorAt
+	for _, m := range checkedAnchor.FindAllStringSubmatchIndex(contents, -1) {
+		for g := 1; g <= 3; g++ {
+			if m[2*g] >= 0 {
+				name := contents[m[2*g]:m[2*g+1]]
+				page.anchors[name]++
+				sections = append(sections, anchorAt{name, m[0]})
+			}
+		}
+	}
+	if markdown {
+		slugs := make(map[string]int)
+		for _, m := range checkedHeading.FindAllStringSubmatch(contents, -1) {
+			slug := githubSlug(m[1])
+			if n := slugs[slug]; n > 0 {
+				page.anchors[fmt.Sprintf("%s-%d", slug, n)]++
+			} else if page.anchors[slug] == 0 {
+				page.anchors[slug]++
+			}
+			slugs[slug]++
+		}
+	}
+	// The section of a link in an index table is the section its row links to, otherwise it's the
+	// section the link is in.
+	section := func(at int) string {
+		start, end := strings.LastIndex(contents[:at], "\n")+1, strings.Index(contents[at:], "\n")
+		row := strings.HasPrefix(strings.TrimSpace(contents[start:at]), "|")
+		if tr := strings.LastIndex(contents[:at], "<tr>"); tr > strings.LastIndex(contents[:at], "</tr>") {
+			start, end, row = tr, strings.Index(contents[at:], "</tr>"), true
+		}
+		if end < 0 {
+			end = len(contents) - at
+		}
+		if m := rowAnchor.FindStringSubmatch(contents[start : at+end]); row && m != nil {
+			return m[1]
+		}
+		i := sort.Search(len(sections), func(i int) bool { return sections[i].at > at })
+		if i == 0 {
+			return ""
+		}
+		return sections[i-1].name
+	}
+	var links [][]int
+	for _, re := range []*regexp.Regexp{checkedMarkdown, checkedHTML, checkedClick} {
+		links = append(links, re.FindAllStringSubmatchIndex(contents, -1)...)
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i][2] < links[j][2] })
+	for _, m := range links {
+		page.refs = append(page.refs, pageRef{link: contents[m[2]:m[3]], section: section(m[0])})
+	}
+	return page
+}
+
+// CheckLinks checks that every relative link in the markdown and html pages in dir resolves to a file (and
+// to an anchor on the page it links to), and that no anchor is defined twice on a page.
+// External links aren't checked.
+func CheckLinks(fs afero.Fs, dir string) ([]BrokenLink, error) {
+	pages := make(map[string]checkedPage)
+	var pageNames []string
+	if err := afero.Walk(fs, dir, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !isPage(filename) {
+			return err
+		}
+		contents, err := afero.ReadFile(fs, filename)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		ext := strings.ToLower(path.Ext(filename))
+		pages[name] = readCheckedPage(string(contents), ext == ".md" || ext == ".markdown")
+		pageNames = append(pageNames, name)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Strings(pageNames)
+
+	var broken []BrokenLink
+	for _, name := range pageNames {
+		page := pages[name]
+		for _, anchor := range SortedKeys(page.anchors) {
+			if page.anchors[anchor] > 1 {
+				broken = append(broken, BrokenLink{Page: name, Section: anchor, Link: "#" + anchor,
+					Reason: fmt.Sprintf("anchor is defined %d times", page.anchors[anchor])})
+			}
+		}
+		for _, ref := range page.refs {
+			if reason := checkLink(fs, dir, name, ref.link, pages); reason != "" {
+				broken = append(broken, BrokenLink{Page: name, Section: ref.section, Link: ref.link, Reason: reason})
+			}
+		}
+	}
+	return broken, nil
+}
+
+// checkLink returns why link on the page named from doesn't resolve, or "" if it does.
+func checkLink(fs afero.Fs, dir, from, link string, pages map[string]checkedPage) string {
+	if link == "" || strings.HasPrefix(link, "//") || strings.Contains(strings.SplitN(link, "#", 2)[0], ":") {
+		return ""
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return "invalid link: " + err.Error()
+	}
+	if u.Scheme != "" || u.Host != "" {
+		return ""
+	}
+	target := from
+	if u.Path != "" {
+		if strings.HasPrefix(u.Path, "/") {
+			target = strings.TrimPrefix(path.Clean(u.Path), "/")
+		} else {
+			target = path.Join(path.Dir(from), u.Path)
+		}
+		if strings.HasPrefix(target, "../") || target == ".." {
+			return "links outside of " + dir
+		}
+		info, err := fs.Stat(path.Join(dir, target))
+		if err != nil {
+			return "file does not exist"
+		}
+		if info.IsDir() {
+			index := ""
+			for _, name := range []string{"index.html", "README.md"} {
+				if _, ok := pages[path.Join(target, name)]; ok {
+					index = path.Join(target, name)
+				}
+			}
+			if index == "" {
+				return "directory has no index page"
+			}
+			target = index
+		}
+	}
+	fragment := u.Fragment
+	if fragment == "" {
+		return ""
+	}
+	page, ok := pages[target]
+	if !ok {
+		return ""
+	}
+	if page.anchors[fragment] == 0 {
+		return fmt.Sprintf("anchor %q does not exist on %s", fragment, target)
+	}
+	return ""
+}
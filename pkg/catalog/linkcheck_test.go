@@ -0,0 +1,66 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLinks(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "out/README.md", []byte(`# Project
+| Package |
+| - |
+| [Orders](Orders/README.md) |
+| [Missing](Missing/README.md) |
+[Heading](#project) [Duplicate](#Dup)
+<a name=Dup></a><a name=Dup></a>
+`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "out/Orders/README.md", []byte(`# Orders
+| App | Endpoint | Source |
+| - | - | - |
+| Orders | [PlaceOrder](#Orders-PlaceOrder) | [orders.sysl](orders.sysl) |
+## <a name=Orders-PlaceOrder></a>Orders PlaceOrder
+[Type](#Orders.Order) [Up](../README.md#Dup) [Project](..) [External](https://example.com/x#y)
+`), 0644))
+
+	broken, err := CheckLinks(fs, "out")
+	require.NoError(t, err)
+	var got []string
+	for _, link := range broken {
+		got = append(got, link.String())
+	}
+	assert.Equal(t, []string{
+		"Orders/README.md#Orders-PlaceOrder: orders.sysl: file does not exist",
+		`Orders/README.md#Orders-PlaceOrder: #Orders.Order: anchor "Orders.Order" does not exist on Orders/README.md`,
+		"README.md#Dup: #Dup: anchor is defined 2 times",
+		"README.md: Missing/README.md: file does not exist",
+	}, got)
+}
+
+func TestCheckLinksGenerated(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("temp.sysl", plantumlService, "html", logrus.New(), m, fs, "out")
+	p.Run()
+
+	broken, err := CheckLinks(fs, "out")
+	require.NoError(t, err)
+	var sections []string
+	for _, link := range broken {
+		// The source files aren't copied to the output.
+		assert.Equal(t, "/temp.sysl", link.Link, link.String())
+		sections = append(sections, link.Section)
+	}
+	require.NotEmpty(t, broken)
+	assert.Contains(t, sections, "Orders-PlaceOrder")
+}
@@ -0,0 +1,279 @@
+// links.go: links from diagram nodes to the package pages and anchors that document them
+package catalog
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/mermaid"
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+var (
+	plantumlComponent = regexp.MustCompile(`^\[(.+)\] as (_\d+)( <<highlight>>)?$`)
+	plantumlPackage   = regexp.MustCompile(`^package "(.+)" \{$`)
+	plantumlTopState  = regexp.MustCompile(`^state "(.+)" as (X_\d+)( <<highlight>>)? \{$`)
+	plantumlState     = regexp.MustCompile(`^  state "(.+)" as (_\d+)( <<highlight>>)?$`)
+	mermaidSubgraph   = regexp.MustCompile(`^\s*subgraph \d+\["(.+)"\]$`)
+	mermaidNodeID     = regexp.MustCompile(`^\w+(-\w+)?$`) // ids mermaid can parse in a click directive
+	plantumlClass     = regexp.MustCompile(`^class "(.+)" as (_\d+) ?<< \(\w,[^)]*\) (.*?) ?>> \{$`)
+)
+
+// typeDoc is the page (relative to OutputDir) and anchor a type is documented at.
+type typeDoc struct {
+	Page   string
+	Anchor string
+}
+
+// WithBaseURL sets the url the output is published at; links in PlantUML diagrams are made absolute with it
+// because the diagrams are rendered by the PlantUML service and relative links would point there.
+func (p *Generator) WithBaseURL(baseURL string) *Generator {
+	p.BaseURL = baseURL
+	return p
+}
+
+// appPages returns the page (relative to OutputDir) of every app that has one, keyed by app name.
+// The pages are computed the same way as MacroPackages and Packages lay them out.
+func (p *Generator) appPages() map[string]string {
+	pages := make(map[string]string)
+	if p.RootModule == nil {
+		return pages
+	}
+	macroPackages := map[string]*sysl.Module{"": p.RootModule}
+	if p.StartTemplateIndex == 0 {
+		macroPackages = p.ModuleAsMacroPackage(p.RootModule)
+	}
+	for macroPackageName, macroPackage := range macroPackages {
+		for packageName, pkg := range p.ModuleAsPackages(macroPackage) {
+			page := path.Join(macroPackageName, packageName, markdownName(p.OutputFileName, packageName))
+			for appName := range pkg.GetApps() {
+				pages[appName] = page
+			}
+		}
+	}
+	return pages
+}
+
+// typeDocs returns where every type of the apps that have a page is documented, keyed by "App.Type".
+// Types are documented on the page of their app, the types of databases in the section of the database.
+func (p *Generator) typeDocs() map[string]typeDoc {
+	docs := make(map[string]typeDoc)
+	for appName, page := range p.appPages() {
+		app := p.RootModule.GetApps()[appName]
+		for typeName := range app.GetTypes() {
+			doc := typeDoc{Page: page, Anchor: SanitiseOutputName(appName) + "." + SanitiseOutputName(typeName)}
+			if syslutil.HasPattern(app.GetAttrs(), "db") {
+				doc.Anchor = "Database-" + SanitiseOutputName(appName)
+			}
+			docs[appName+"."+typeName] = doc
+		}
+	}
+	return docs
+}
+
+// typeLink returns a relative link from the page being generated to the documentation of a type
+// ("App.Type"); types that aren't in the registry are assumed to be on the same page.
+func (p *Generator) typeLink(reference string) string {
+	doc, ok := p.TypeDocs[reference]
+	if !ok {
+		return "#" + SanitiseOutputName(reference)
+	}
+	return p.anchorLink(doc.Page, doc.Anchor)
+}
+
+// anchorLink returns a link to anchor on page (relative to OutputDir), which is just the anchor on the page
+// being generated.
+func (p *Generator) anchorLink(page, anchor string) string {
+	if path.Dir(page) == path.Clean(p.CurrentDir) {
+		return "#" + anchor
+	}
+	return p.relativeLink(page, anchor)
+}
+
+// linkDataModel adds [[url]] links to the classes (types) of a plantuml data model of the types of app, and
+// colors them by their patterns.
+func (p *Generator) linkDataModel(appName, plantumlString string) string {
+	if len(p.TypeDocs) == 0 && len(p.Theme.Patterns) == 0 {
+		return plantumlString
+	}
+	lines := strings.Split(plantumlString, "\n")
+	for i, line := range lines {
+		match := plantumlClass.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		reference := match[3]
+		if !strings.Contains(reference, ".") {
+			reference = appName + "." + reference
+		}
+		var link string
+		if doc, ok := p.TypeDocs[reference]; ok {
+			link = fmt.Sprintf(" [[%s]]", p.pageLink(doc.Page, doc.Anchor))
+		}
+		if suffix := link + plantumlColor(p.typeColor(reference)); suffix != "" {
+			lines[i] = strings.TrimSuffix(line, " {") + suffix + " {"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pageLink returns a link from the page being generated (in CurrentDir) to anchor on page.
+func (p *Generator) pageLink(page, anchor string) string {
+	if p.BaseURL != "" {
+		return strings.TrimSuffix(p.BaseURL, "/") + "/" + (&url.URL{Path: page, Fragment: anchor}).String()
+	}
+	return p.relativeLink(page, anchor)
+}
+
+// relativeLink returns a link relative to the page being generated (in CurrentDir) to anchor on page.
+func (p *Generator) relativeLink(page, anchor string) string {
+	rel, err := filepath.Rel(path.Join("/", p.CurrentDir), path.Join("/", page))
+	if err != nil {
+		rel = page
+	}
+	return (&url.URL{Path: filepath.ToSlash(rel), Fragment: anchor}).String()
+}
+
+// appLink returns a link to the section of app on its package page, or "" if the app has no page.
+func (p *Generator) appLink(pages map[string]string, appName string) string {
+	page, ok := pages[appName]
+	if !ok {
+		return ""
+	}
+	if syslutil.HasPattern(p.RootModule.GetApps()[appName].GetAttrs(), "db") {
+		return p.pageLink(page, "Database-"+SanitiseOutputName(appName))
+	}
+	return p.pageLink(page, SanitiseOutputName(appName))
+}
+
+// endpointLink returns a link to the documentation of an endpoint, or "" if its app has no page.
+func (p *Generator) endpointLink(pages map[string]string, appName, endpointName string) string {
+	page, ok := pages[appName]
+	if !ok {
+		return ""
+	}
+	return p.pageLink(page, p.endpointAnchor(appName, endpointName))
+}
+
+// endpointAnchor returns the anchor of the documentation of an endpoint on its package page.
+func (p *Generator) endpointAnchor(appName, endpointName string) string {
+	if IsEvent(p.RootModule.GetApps()[appName].GetEndpoints()[endpointName]) {
+		return "Event-" + SanitiseOutputName(appName) + "-" + SanitiseOutputName(endpointName)
+	}
+	return SanitiseOutputName(appName) + "-" + SanitiseOutputName(endpointName)
+}
+
+// linkPlantuml adds [[url]] links to the components (apps) and states (endpoints) of integration
+// and endpoint analysis diagrams, and colors the apps by their patterns. The links work where the SVG is
+// embedded as an object (see embedPlantuml) or opened on its own, not in images.
+func (p *Generator) linkPlantuml(pages map[string]string, plantumlString string) string {
+	lines := strings.Split(plantumlString, "\n")
+	var cluster, app string
+	for i, line := range lines {
+		switch {
+		case plantumlPackage.MatchString(line):
+			cluster = plantumlPackage.FindStringSubmatch(line)[1]
+		case plantumlComponent.MatchString(line):
+			appName := plantumlComponent.FindStringSubmatch(line)[1]
+			if cluster != "" {
+				appName = cluster + namespaceSeparator + appName
+			}
+			if link := p.appLink(pages, appName); link != "" {
+				lines[i] = fmt.Sprintf("%s [[%s]]", line, link)
+			}
+			lines[i] += plantumlColor(p.appColor(appName))
+		case plantumlTopState.MatchString(line):
+			app = plantumlTopState.FindStringSubmatch(line)[1]
+			if link := p.appLink(pages, app); link != "" {
+				lines[i] = fmt.Sprintf("%s [[%s]] {", strings.TrimSuffix(line, " {"), link)
+			}
+			if color := plantumlColor(p.appColor(app)); color != "" {
+				lines[i] = strings.TrimSuffix(lines[i], " {") + color + " {"
+			}
+		case plantumlState.MatchString(line):
+			endpointName := plantumlState.FindStringSubmatch(line)[1]
+			if _, ok := p.RootModule.GetApps()[app].GetEndpoints()[endpointName]; !ok {
+				continue
+			}
+			if link := p.endpointLink(pages, app, endpointName); link != "" {
+				lines[i] = fmt.Sprintf("%s [[%s]]", line, link)
+			}
+		case line == "}":
+			cluster, app = "", ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// linkMermaid appends click directives to integration and endpoint analysis diagrams so that app and
+// endpoint nodes link to their documentation.
+func (p *Generator) linkMermaid(pages map[string]string, mermaidString string) string {
+	links := make(map[string]string)
+	var ids []string
+	addLink := func(id, link string) {
+		if _, ok := links[id]; ok || link == "" || !mermaidNodeID.MatchString(id) {
+			return
+		}
+		links[id] = link
+		ids = append(ids, id)
+	}
+	// Endpoints that are called are named "App-Endpoint", apps are named after themselves.
+	nodes := make(map[string]func() string)
+	for appName, app := range p.RootModule.GetApps() {
+		appName := appName
+		nodes[strings.ReplaceAll(appName, " ", "_")] = func() string { return p.appLink(pages, appName) }
+		for endpointName := range app.GetEndpoints() {
+			endpointName := endpointName
+			nodes[mermaid.CleanString(appName)+"-"+mermaid.CleanString(endpointName)] = func() string {
+				return p.endpointLink(pages, appName, endpointName)
+			}
+		}
+	}
+	var app string
+	for _, line := range strings.Split(mermaidString, "\n") {
+		if match := mermaidSubgraph.FindStringSubmatch(line); match != nil {
+			app = match[1]
+			continue
+		}
+		if strings.TrimSpace(line) == "end" {
+			app = ""
+			continue
+		}
+		for i, side := range strings.Split(line, "-->") {
+			id := strings.TrimSpace(side)
+			if j := strings.Index(id, "["); j >= 0 {
+				id = id[:j]
+			}
+			if id == "" || strings.HasPrefix(id, "%%") || id == "graph" || strings.HasPrefix(id, "graph ") {
+				continue
+			}
+			// The endpoints of the app in a subgraph are named after the endpoint only.
+			if i == 0 && app != "" {
+				for endpointName := range p.RootModule.GetApps()[app].GetEndpoints() {
+					if mermaid.CleanString(endpointName) == id {
+						addLink(id, p.endpointLink(pages, app, endpointName))
+					}
+				}
+				continue
+			}
+			if link, ok := nodes[id]; ok {
+				addLink(id, link())
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return mermaidString
+	}
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(mermaidString, "\n"))
+	b.WriteString("\n")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "    click %s \"%s\"\n", id, links[id])
+	}
+	return b.String()
+}
@@ -0,0 +1,150 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppPages(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	p := NewProject("test", plantumlService, "html", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	pages := p.appPages()
+	assert.Equal(t, "Orders/index.html", pages["Orders"])
+	assert.Equal(t, "Fulfilment/index.html", pages["Shipping"])
+
+	p.CurrentDir = "Fulfilment"
+	assert.Equal(t, "../Orders/index.html#Orders-PlaceOrder", p.endpointLink(pages, "Orders", "PlaceOrder"))
+	assert.Equal(t, "../Orders/index.html#Event-Orders-OrderPlaced", p.endpointLink(pages, "Orders", "OrderPlaced"))
+	assert.Equal(t, "index.html#Warehouse", p.appLink(pages, "Warehouse"))
+
+	p.WithBaseURL("https://example.com/docs/")
+	assert.Equal(t, "https://example.com/docs/Fulfilment/index.html#Warehouse", p.appLink(pages, "Warehouse"))
+}
+
+func TestLinkMermaid(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	p := NewProject("test", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	diagram := p.IntegrationMermaid(m, "test", false)
+	assert.Contains(t, diagram, `click Shipping "Fulfilment/README.md#Shipping"`)
+	assert.Contains(t, diagram, `click Orders "Orders/README.md#Orders"`)
+}
+
+func TestLinkPlantuml(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	p := NewProject("test", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	diagram := p.linkPlantuml(p.appPages(), `@startuml
+[Shipping] as _0 <<highlight>>
+[Unknown] as _1
+state "Orders" as X_0 {
+  state "PlaceOrder" as _2
+  state "PlaceOrder client" as _3
+}
+@enduml`)
+	assert.Equal(t, `@startuml
+[Shipping] as _0 <<highlight>> [[Fulfilment/README.md#Shipping]]
+[Unknown] as _1
+state "Orders" as X_0 [[Orders/README.md#Orders]] {
+  state "PlaceOrder" as _2 [[Orders/README.md#Orders-PlaceOrder]]
+  state "PlaceOrder client" as _3
+}
+@enduml`, diagram)
+}
+
+const typesSysl = `
+A:
+    @package = "PA"
+    !type Thing:
+        o <: B.Order
+        n <: sequence of Other
+    !type Other:
+        x <: int
+B:
+    @package = "PB"
+    !type Order:
+        id <: int
+`
+
+func TestEmbedPlantuml(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("test", plantumlService, "html", logrus.New(), m, fs, "out").
+		WithBaseURL("https://example.com/docs")
+	p.Templates = nil
+	p.WithTemplateString(MacroPackageProject, ProjectTemplate, NewPackageTemplate).Run()
+
+	b, err := afero.ReadFile(fs, "out/index.html")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `<object type="image/svg+xml" data="`+plantumlService+`/svg/~1`)
+	assert.NotContains(t, string(b), `<img src="`+plantumlService)
+
+	assert.Equal(t, `<p><img src="diagram.svg" alt=""></p>`, p.embedPlantuml(`<p><img src="diagram.svg" alt=""></p>`))
+}
+
+func TestTypeLinks(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(typesSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out")
+	p.Run()
+
+	assert.Equal(t, typeDoc{Page: "PB/README.md", Anchor: "B.Order"}, p.TypeDocs["B.Order"])
+	b, err := afero.ReadFile(fs, "out/PA/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `| o | <a href="../PB/README.md#B.Order">Order</a> (tuple) |  |`)
+	assert.Contains(t, string(b), `| n | sequence of <a href="#A.Other">Other</a> |  |`)
+}
+
+func TestTypeLinksPlantuml(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(typesSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out")
+	p.Templates = nil
+	p.WithTemplateString(MacroPackageProject, ProjectTemplate, NewPackageTemplate).Run()
+
+	b, err := afero.ReadFile(fs, "out/PA/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `| o | <a href="../PB/README.md#B.Order">Order</a> | |`)
+	assert.Contains(t, string(b), `| n | sequence of <a href="#A.Other">Other</a> | |`)
+	assert.Contains(t, string(b), `| x | int | |`)
+}
+
+func TestLinkDataModel(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(typesSysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+	p.TypeDocs = p.typeDocs()
+	p.CurrentDir = "PA"
+
+	assert.Equal(t, `class "Thing" as _1 << (D,orchid) Thing >> [[README.md#A.Thing]] {
+class "o" as _2 << (D,orchid) B.Order >> [[../PB/README.md#B.Order]] {
+class "x" as _3 << (D,orchid) C.Unknown >> {`, p.linkDataModel("A", `class "Thing" as _1 << (D,orchid) Thing >> {
+class "o" as _2 << (D,orchid) B.Order >> {
+class "x" as _3 << (D,orchid) C.Unknown >> {`))
+}
@@ -0,0 +1,151 @@
+// merge.go: combines the modules of several root sysl files into a single module
+package catalog
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"google.golang.org/protobuf/proto"
+)
+
+// Project is a ~project application declared in one of the root sysl files.
+type Project struct {
+	Name        string
+	Source      string
+	Description string
+	Packages    []string // The endpoints of the project app; each one is a macro package
+}
+
+// MergeModules combines modules into a single module.
+// An app can be part of more than one module (eg. when two roots import the same file) as long as
+// all of its definitions are identical, otherwise an error naming both source files is returned.
+func MergeModules(modules ...*sysl.Module) (*sysl.Module, error) {
+	merged := &sysl.Module{Apps: map[string]*sysl.Application{}}
+	var conflicts []string
+	for _, m := range modules {
+		for appName, app := range m.GetApps() {
+			existing, ok := merged.Apps[appName]
+			if !ok {
+				merged.Apps[appName] = app
+				continue
+			}
+			if !proto.Equal(existing, app) {
+				conflicts = append(conflicts, fmt.Sprintf("%s (%s and %s)",
+					appName, sourceFile(existing), sourceFile(app)))
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("apps defined differently by more than one input: %s", strings.Join(conflicts, ", "))
+	}
+	return merged, nil
+}
+
+func sourceFile(app *sysl.Application) string {
+	if ctx := app.GetSourceContext(); ctx != nil {
+		return ctx.GetFile()
+	}
+	return "unknown"
+}
+
+// WithSourceFiles sets the root sysl files of a project; ~project apps declared in any of them are used.
+func (p *Generator) WithSourceFiles(fileNames ...string) *Generator {
+	p.SourceFileNames = fileNames
+	if p.RootModule != nil && !p.CustomTemplate {
+		if len(p.ModuleAsMacroPackage(p.RootModule)) <= 1 {
+			p.StartTemplateIndex = 1 // skip the MacroPackageProject
+		} else {
+			p.StartTemplateIndex = 0
+		}
+	}
+	return p
+}
+
+// WithSourceFormats sets the format each root file was loaded in (FormatSysl, FormatPb...), keyed by file, for
+// roots whose format can't be told from their extension.
+func (p *Generator) WithSourceFormats(formats map[string]string) *Generator {
+	p.SourceFormats = formats
+	return p
+}
+
+// WithCompiledApps sets the apps that were loaded from compiled modules (the roots that aren't sysl files), whose
+// ~project apps are root projects as a compiled module doesn't know which file was its root.
+func (p *Generator) WithCompiledApps(apps map[string]bool) *Generator {
+	unlock := p.lock()
+	defer unlock()
+	p.CompiledApps = apps
+	return p
+}
+
+// rootFormat returns the format root was loaded in, guessed from its extension if it wasn't set.
+func (p *Generator) rootFormat(root string) string {
+	if format, ok := p.SourceFormats[root]; ok {
+		return format
+	}
+	return InputFormat(root)
+}
+
+// rootFiles returns the root sysl files of the project.
+func (p *Generator) rootFiles() []string {
+	if len(p.SourceFileNames) > 0 {
+		return p.SourceFileNames
+	}
+	return []string{p.SourceFileName}
+}
+
+// isRootProject returns true for ~project apps declared in one of the root sysl files.
+func (p *Generator) isRootProject(app *sysl.Application) bool {
+	if !syslutil.HasPattern(app.GetAttrs(), "project") {
+		return false
+	}
+	if p.CompiledApps != nil {
+		if p.CompiledApps[GetAppNameString(app)] {
+			return true
+		}
+	} else {
+		// Without knowing which apps were compiled, every app is assumed to be from the compiled roots.
+		for _, root := range p.rootFiles() {
+			if p.rootFormat(root) != FormatSysl {
+				return true
+			}
+		}
+	}
+	for _, ctx := range app.SourceContexts {
+		for _, root := range p.rootFiles() {
+			if path.Clean(ctx.File) == path.Clean(root) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RootProjects returns the ~project apps of every root sysl file, so that a project made from
+// several roots can list where each of its macro packages came from.
+func (p *Generator) RootProjects() []Project {
+	var projects []Project
+	if p.RootModule == nil {
+		return nil
+	}
+	for _, name := range SortedKeys(p.RootModule.GetApps()) {
+		app := p.RootModule.GetApps()[name]
+		if !p.isRootProject(app) {
+			continue
+		}
+		project := Project{
+			Name:        name,
+			Source:      p.SourcePath(app),
+			Description: Attribute(app, "description"),
+		}
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			if !syslutil.HasPattern(app.GetEndpoints()[endpointName].GetAttrs(), "ignore") {
+				project.Packages = append(project.Packages, endpointName)
+			}
+		}
+		projects = append(projects, project)
+	}
+	return projects
+}
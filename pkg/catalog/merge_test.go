@@ -0,0 +1,135 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeModules(t *testing.T) {
+	t.Parallel()
+
+	a, err := parse.NewParser().ParseString(`
+A:
+    Endpoint: ...
+Shared:
+    Endpoint: ...
+`)
+	require.NoError(t, err)
+	b, err := parse.NewParser().ParseString(`
+B:
+    Endpoint: ...
+Shared:
+    Endpoint: ...
+`)
+	require.NoError(t, err)
+
+	m, err := MergeModules(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A", "B", "Shared"}, SortedKeys(m.GetApps()))
+}
+
+func TestMergeModulesConflict(t *testing.T) {
+	t.Parallel()
+
+	a, err := parse.NewParser().ParseString(`
+Shared:
+    Endpoint: ...
+`)
+	require.NoError(t, err)
+	b, err := parse.NewParser().ParseString(`
+Shared:
+    OtherEndpoint: ...
+`)
+	require.NoError(t, err)
+
+	_, err = MergeModules(a, b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Shared")
+}
+
+func TestRootProjects(t *testing.T) {
+	t.Parallel()
+
+	roots := []string{"../../tests/project_a.sysl", "../../tests/project_b.sysl"}
+	a, err := parse.NewParser().Parse(roots[0], AferoRetriever{afero.NewOsFs()})
+	require.NoError(t, err)
+	b, err := parse.NewParser().Parse(roots[1], AferoRetriever{afero.NewOsFs()})
+	require.NoError(t, err)
+	m, err := MergeModules(a, b)
+	require.NoError(t, err)
+
+	p := NewProject("tests", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out").
+		WithSourceFiles(roots...)
+	projects := p.RootProjects()
+	require.Len(t, projects, 2)
+	assert.Equal(t, "ProjA", projects[0].Name)
+	assert.Equal(t, []string{"Alpha"}, projects[0].Packages)
+	assert.Equal(t, "ProjB", projects[1].Name)
+	assert.Equal(t, []string{"Alpha", "Beta"}, SortedKeys(p.ModuleAsMacroPackage(m)))
+	assert.Equal(t, 0, p.StartTemplateIndex)
+}
+
+func TestRootProjectsSourceFormats(t *testing.T) {
+	t.Parallel()
+
+	a, err := parse.NewParser().Parse("../../tests/project_a.sysl", AferoRetriever{afero.NewOsFs()})
+	require.NoError(t, err)
+	b, err := parse.NewParser().Parse("../../tests/project_b.sysl", AferoRetriever{afero.NewOsFs()})
+	require.NoError(t, err)
+	m, err := MergeModules(a, b)
+	require.NoError(t, err)
+
+	// A compiled module without an extension would be guessed to be a sysl file that declares no ~project apps.
+	p := NewProject("tests", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out").
+		WithSourceFiles("module")
+	assert.Empty(t, p.RootProjects())
+
+	p.WithSourceFormats(map[string]string{"module": FormatPb})
+	projects := p.RootProjects()
+	require.Len(t, projects, 2)
+	assert.Equal(t, "ProjA", projects[0].Name)
+	assert.Equal(t, "ProjB", projects[1].Name)
+}
+
+func TestRootProjectsSameBasename(t *testing.T) {
+	t.Parallel()
+
+	a, err := parse.NewParser().Parse("../../tests/project_a.sysl", AferoRetriever{afero.NewOsFs()})
+	require.NoError(t, err)
+	b, err := parse.NewParser().Parse("../../tests/project_b.sysl", AferoRetriever{afero.NewOsFs()})
+	require.NoError(t, err)
+	m, err := MergeModules(a, b)
+	require.NoError(t, err)
+
+	// A root in another directory with the same file name as an import doesn't make the import a root.
+	p := NewProject("tests", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out").
+		WithSourceFiles("../../tests/project_a.sysl", "other/project_b.sysl")
+	projects := p.RootProjects()
+	require.Len(t, projects, 1)
+	assert.Equal(t, "ProjA", projects[0].Name)
+}
+
+func TestRootProjectsCompiledApps(t *testing.T) {
+	t.Parallel()
+
+	a, err := parse.NewParser().Parse("../../tests/project_a.sysl", AferoRetriever{afero.NewOsFs()})
+	require.NoError(t, err)
+	b, err := parse.NewParser().Parse("../../tests/project_b.sysl", AferoRetriever{afero.NewOsFs()})
+	require.NoError(t, err)
+	m, err := MergeModules(a, b)
+	require.NoError(t, err)
+
+	// Only the apps that came from the compiled root are its projects.
+	p := NewProject("tests", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out").
+		WithSourceFiles("module").
+		WithSourceFormats(map[string]string{"module": FormatPb}).
+		WithCompiledApps(map[string]bool{"ProjB": true})
+	projects := p.RootProjects()
+	require.Len(t, projects, 1)
+	assert.Equal(t, "ProjB", projects[0].Name)
+}
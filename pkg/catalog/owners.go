@@ -0,0 +1,355 @@
+// owners.go: pages of the apps each team owns, grouped by the owner attributes of the apps, and a CODEOWNERS file
+package catalog
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+	"github.com/spf13/afero"
+)
+
+const (
+	ownersDir      = "owners"     // The directory of the owner pages in the output directory
+	CodeOwnersFile = "CODEOWNERS" // The CODEOWNERS mapping written to the output directory
+)
+
+// DefaultOwnerKeys are the attributes that name the owner of an app, in order of preference.
+var DefaultOwnerKeys = []string{"Owner.Team", "Owner.Name", "Owner.Email", "Owner.Slack"}
+
+// ownerContactKeys are the attributes listed as the contacts of an owner.
+var ownerContactKeys = []string{"Owner.Email", "Owner.Slack"}
+
+var ownerDirChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// codeOwner matches the owners GitHub accepts in CODEOWNERS: @user, @org/team or an email address.
+var codeOwner = regexp.MustCompile(`^(@[A-Za-z0-9-]+(/[A-Za-z0-9._-]+)?|[^@\s]+@[^@\s]+\.[^@\s]+)$`)
+
+// Owner is a team (or person) and everything it owns.
+type Owner struct {
+	Name         string
+	Dir          string   // The directory of the page of the owner in the owners directory
+	Link         string   // The page of the owner
+	Contacts     []string // The distinct Owner.Email and Owner.Slack attributes of its apps
+	Apps         []OwnedApp
+	Dependencies []OwnerDependency // Calls from its apps to apps of other owners
+}
+
+// OwnedApp is an app and its endpoints.
+type OwnedApp struct {
+	Name      string
+	Link      string
+	Database  bool
+	Endpoints []Ranked // The endpoints and their links; Count is unused
+}
+
+// OwnerDependency is a call from an app of an owner to an app of another owner ("" if it has none).
+type OwnerDependency struct {
+	From      string
+	To        string
+	ToLink    string
+	Owner     string
+	OwnerLink string
+}
+
+// WithOwnerKeys sets the attributes that name the owner of an app, DefaultOwnerKeys if empty.
+func (p *Generator) WithOwnerKeys(keys ...string) *Generator {
+	p.OwnerKeys = keys
+	return p
+}
+
+// WithCodeOwners writes a CODEOWNERS mapping of the sysl files to the owners of their apps.
+func (p *Generator) WithCodeOwners(codeOwners bool) *Generator {
+	p.CodeOwners = codeOwners
+	return p
+}
+
+// ownerKeys returns the attributes that name the owner of an app.
+func (p *Generator) ownerKeys() []string {
+	if len(p.OwnerKeys) == 0 {
+		return DefaultOwnerKeys
+	}
+	return p.OwnerKeys
+}
+
+// attributeFold returns the first of keys that a has (matched case insensitively), or "".
+func attributeFold(a Attr, keys ...string) string {
+	for _, key := range keys {
+		for attrName := range a.GetAttrs() {
+			if strings.EqualFold(attrName, key) {
+				if value := Attribute(a, attrName); value != "" {
+					return value
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// OwnerOf returns the owner of an app, or "" if it has none.
+func (p *Generator) OwnerOf(app *sysl.Application) string {
+	return attributeFold(app, p.ownerKeys()...)
+}
+
+// ownerDir returns the directory of the page of an owner in the owners directory, which may be "".
+func ownerDir(owner string) string {
+	return strings.Trim(ownerDirChars.ReplaceAllString(strings.ToLower(owner), "-"), "-")
+}
+
+// ownerDirs returns a distinct directory for each owner: owners whose names make the same (or no) directory
+// get a hash of their name appended.
+func ownerDirs(owners []string) map[string]string {
+	count := make(map[string]int)
+	for _, owner := range owners {
+		count[ownerDir(owner)]++
+	}
+	dirs := make(map[string]string, len(owners))
+	for _, owner := range owners {
+		dir := ownerDir(owner)
+		if dir == "" || count[dir] > 1 {
+			sum := sha1.Sum([]byte(owner))
+			dir = strings.TrimPrefix(dir+"-"+hex.EncodeToString(sum[:4]), "-")
+		}
+		dirs[owner] = dir
+	}
+	return dirs
+}
+
+// ownerPage returns the page of an owner relative to OutputDir.
+func (p *Generator) ownerPage(owner *Owner) string {
+	return path.Join(ownersDir, owner.Dir, markdownName(p.OutputFileName, owner.Dir))
+}
+
+// Owners returns the owners of the apps that have a page, sorted by name, and the apps without an owner.
+// Links are relative to the page being generated.
+func (p *Generator) Owners() (owners []*Owner, unowned []OwnedApp) {
+	owners, unowned = p.owners()
+	for i, owner := range owners {
+		owners[i] = p.relinkOwner(owner)
+	}
+	return owners, p.relinkApps(unowned)
+}
+
+// owners returns the owners and unowned apps of Owners, with links relative to OutputDir.
+func (p *Generator) owners() (owners []*Owner, unowned []OwnedApp) {
+	currentDir := p.CurrentDir
+	defer func() { p.CurrentDir = currentDir }()
+	p.CurrentDir = ""
+	pages := p.appPages()
+	byName := make(map[string]*Owner)
+	ownerOf := make(map[string]string)
+	contacts := make(map[string]map[string]bool)
+	for _, appName := range SortedKeys(pages) {
+		app := p.RootModule.GetApps()[appName]
+		owned := OwnedApp{
+			Name:     appName,
+			Link:     p.appLink(pages, appName),
+			Database: syslutil.HasPattern(app.GetAttrs(), "db"),
+		}
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			if syslutil.HasPattern(app.GetEndpoints()[endpointName].GetAttrs(), "ignore") {
+				continue
+			}
+			owned.Endpoints = append(owned.Endpoints,
+				Ranked{Name: endpointName, Link: p.endpointLink(pages, appName, endpointName)})
+		}
+		name := p.OwnerOf(app)
+		if name == "" {
+			unowned = append(unowned, owned)
+			continue
+		}
+		ownerOf[appName] = name
+		owner, ok := byName[name]
+		if !ok {
+			owner = &Owner{Name: name}
+			byName[name] = owner
+			contacts[name] = make(map[string]bool)
+		}
+		owner.Apps = append(owner.Apps, owned)
+		for _, key := range ownerContactKeys {
+			if contact := attributeFold(app, key); contact != "" && !contacts[name][contact] {
+				contacts[name][contact] = true
+				owner.Contacts = append(owner.Contacts, contact)
+			}
+		}
+	}
+	dirs := ownerDirs(SortedKeys(byName))
+	for name, owner := range byName {
+		owner.Dir = dirs[name]
+		owner.Link = p.relativeLink(p.ownerPage(owner), "")
+	}
+	_, deps := p.dependencies(p.RootModule)
+	for _, dep := range deps {
+		from, ok := byName[ownerOf[dep.From]]
+		if !ok || ownerOf[dep.To] == from.Name {
+			continue
+		}
+		d := OwnerDependency{From: dep.From, To: dep.To, Owner: ownerOf[dep.To]}
+		if _, ok := pages[dep.To]; ok {
+			d.ToLink = p.appLink(pages, dep.To)
+		}
+		if d.Owner != "" {
+			d.OwnerLink = byName[d.Owner].Link
+		}
+		from.Dependencies = append(from.Dependencies, d)
+	}
+	for _, name := range SortedKeys(byName) {
+		owners = append(owners, byName[name])
+	}
+	return owners, unowned
+}
+
+// rebaseLink returns a link relative to OutputDir relative to the page being generated instead.
+func (p *Generator) rebaseLink(link string) string {
+	u, err := url.Parse(link)
+	if link == "" || err != nil || u.IsAbs() {
+		return link
+	}
+	return p.relativeLink(u.Path, u.Fragment)
+}
+
+// relinkApps returns apps with their links (relative to OutputDir) relative to the page being generated.
+func (p *Generator) relinkApps(apps []OwnedApp) []OwnedApp {
+	relinked := make([]OwnedApp, 0, len(apps))
+	for _, app := range apps {
+		endpoints := make([]Ranked, 0, len(app.Endpoints))
+		for _, endpoint := range app.Endpoints {
+			endpoints = append(endpoints, Ranked{Name: endpoint.Name, Link: p.rebaseLink(endpoint.Link)})
+		}
+		app.Link, app.Endpoints = p.rebaseLink(app.Link), endpoints
+		relinked = append(relinked, app)
+	}
+	return relinked
+}
+
+// relinkOwner returns an owner with its links (relative to OutputDir) relative to the page being generated.
+func (p *Generator) relinkOwner(owner *Owner) *Owner {
+	relinked := *owner
+	relinked.Link = p.rebaseLink(owner.Link)
+	relinked.Apps = p.relinkApps(owner.Apps)
+	relinked.Dependencies = make([]OwnerDependency, 0, len(owner.Dependencies))
+	for _, dep := range owner.Dependencies {
+		dep.ToLink, dep.OwnerLink = p.rebaseLink(dep.ToLink), p.rebaseLink(dep.OwnerLink)
+		relinked.Dependencies = append(relinked.Dependencies, dep)
+	}
+	return &relinked
+}
+
+// CreateOwnerPages writes the owners index and a page per owner to owners/, and returns the index page
+// (relative to OutputDir), or "" if no app has an owner.
+func (p *Generator) CreateOwnerPages() string {
+	owners, _ := p.owners()
+	if len(owners) == 0 {
+		return ""
+	}
+	indexPage := p.createExtraPage(ownersDir, "Owners", OwnersTemplate, func() interface{} {
+		owners, unowned := p.Owners()
+		return struct {
+			*Generator
+			Owners  []*Owner
+			Unowned []OwnedApp
+		}{p, owners, unowned}
+	})
+	if indexPage == "" {
+		return ""
+	}
+	links := map[string]string{"Owners": "../" + markdownName(p.OutputFileName, ownersDir)}
+	for _, owner := range owners {
+		owner := owner
+		p.createPage(p.ownerPage(owner), owner.Name, links, OwnerTemplate, func() interface{} {
+			return struct {
+				*Generator
+				Owner *Owner
+			}{p, p.relinkOwner(owner)}
+		})
+	}
+	return indexPage
+}
+
+// codeOwnerOf returns the owner of an app as CODEOWNERS names it: the first of its Owner.Email attribute and
+// its owner attributes that is a GitHub user or team (@user or @org/team) or an email address, or "".
+func (p *Generator) codeOwnerOf(app *sysl.Application) string {
+	for _, key := range append([]string{"Owner.Email"}, p.ownerKeys()...) {
+		if owner := attributeFold(app, key); codeOwner.MatchString(owner) {
+			return owner
+		}
+	}
+	return ""
+}
+
+// CreateCodeOwners writes a CODEOWNERS mapping of every local sysl file to the owners of the apps defined
+// in it. Apps whose owner isn't a valid CODEOWNERS owner (see codeOwnerOf) are left out, with a warning.
+func (p *Generator) CreateCodeOwners() error {
+	owners := make(map[string]map[string]bool)
+	for _, appName := range SortedKeys(p.RootModule.GetApps()) {
+		app := p.RootModule.GetApps()[appName]
+		file := app.GetSourceContext().GetFile()
+		if file == "" || app.GetSourceContext().GetVersion() != "" {
+			continue // Imported from another repository
+		}
+		owner := p.codeOwnerOf(app)
+		if owner == "" {
+			if name := p.OwnerOf(app); name != "" {
+				p.Log.Warnf("%s isn't a CODEOWNERS owner (@user, @org/team or an email), so %s is left out of %s",
+					name, appName, CodeOwnersFile)
+			}
+			continue
+		}
+		if owners[file] == nil {
+			owners[file] = make(map[string]bool)
+		}
+		owners[file][owner] = true
+	}
+	var b strings.Builder
+	b.WriteString("# Automatically generated by sysl-catalog from the owners of the applications in each file\n")
+	for _, file := range SortedKeys(owners) {
+		fmt.Fprintf(&b, "/%s %s\n", strings.TrimPrefix(path.Clean(file), "/"), strings.Join(SortedKeys(owners[file]), " "))
+	}
+	return afero.WriteFile(p.Fs, path.Join(p.OutputDir, CodeOwnersFile), []byte(b.String()), 0644)
+}
+
+// OwnersTemplate is the owners index.
+const OwnersTemplate = `
+{{/* Automatically generated by https://github.com/anz-bank/sysl-catalog it is strongly recommended not to edit this file */}}
+{{range $name, $link := .Links}} [{{$name}}]({{$link}}) | {{end}}
+# {{.Title}}
+
+| Owner | Applications | Contacts |
+|----|----|----|{{range $owner := .Owners}}
+| [{{$owner.Name}}]({{$owner.Link}}) | {{len $owner.Apps}} | {{join ", " $owner.Contacts}} |{{end}}
+{{if .Unowned}}
+## Unowned Applications
+| Application |
+|----|{{range $app := .Unowned}}
+| [{{$app.Name}}]({{$app.Link}}) |{{end}}
+{{end}}`
+
+// OwnerTemplate is the page of an owner.
+const OwnerTemplate = `
+{{/* Automatically generated by https://github.com/anz-bank/sysl-catalog it is strongly recommended not to edit this file */}}
+{{range $name, $link := .Links}} [{{$name}}]({{$link}}) | {{end}}
+# {{.Title}}
+{{range $contact := .Owner.Contacts}}
+- {{$contact}}{{end}}
+
+## Applications
+| Application | Endpoints |
+|----|----|{{range $app := .Owner.Apps}}{{if not $app.Database}}
+| [{{$app.Name}}]({{$app.Link}}) | {{range $i, $e := $app.Endpoints}}{{if $i}}, {{end}}[{{$e.Name}}]({{$e.Link}}){{end}} |{{end}}{{end}}
+
+## Databases
+| Database |
+|----|{{range $app := .Owner.Apps}}{{if $app.Database}}
+| [{{$app.Name}}]({{$app.Link}}) |{{end}}{{end}}
+
+## Dependencies on Other Teams
+| Application | Calls | Owner |
+|----|----|----|{{range $dep := .Owner.Dependencies}}
+| {{$dep.From}} | {{if $dep.ToLink}}[{{$dep.To}}]({{$dep.ToLink}}){{else}}{{$dep.To}}{{end}} | {{if $dep.OwnerLink}}[{{$dep.Owner}}]({{$dep.OwnerLink}}){{else}}Unowned{{end}} |{{end}}
+`
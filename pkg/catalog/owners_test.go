@@ -0,0 +1,189 @@
+package catalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ownersSysl = `
+Orders:
+    @package = "Orders"
+    @owner.team = "Order Team"
+    @owner.email = "orders@example.com"
+    PlaceOrder:
+        Stock <- Reserve
+        Payments <- Pay
+    Cancel:
+        OrdersDB <- Delete
+OrdersDB[~db]:
+    @package = "Orders"
+    @owner.team = "Order Team"
+    Delete: ...
+Stock:
+    @package = "Stock"
+    @owner.email = "stock@example.com"
+    Reserve: ...
+Payments:
+    @package = "Payments"
+    Pay: ...
+`
+
+func ownersProject(t *testing.T) (*Generator, afero.Fs) {
+	m, err := parse.NewParser().ParseString(ownersSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	return NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out"), fs
+}
+
+func TestOwners(t *testing.T) {
+	t.Parallel()
+
+	p, fs := ownersProject(t)
+	p.Run()
+
+	p.CurrentDir = ownersDir
+	owners, unowned := p.Owners()
+	require.Len(t, owners, 2)
+	orders := owners[0]
+	assert.Equal(t, "Order Team", orders.Name)
+	assert.Equal(t, "order-team/README.md", orders.Link)
+	assert.Equal(t, []string{"orders@example.com"}, orders.Contacts)
+	assert.Equal(t, []OwnedApp{
+		{Name: "Orders", Link: "../Orders/README.md#Orders", Endpoints: []Ranked{
+			{Name: "Cancel", Link: "../Orders/README.md#Orders-Cancel"},
+			{Name: "PlaceOrder", Link: "../Orders/README.md#Orders-PlaceOrder"},
+		}},
+		{Name: "OrdersDB", Link: "../Orders/README.md#Database-OrdersDB", Database: true, Endpoints: []Ranked{
+			{Name: "Delete", Link: "../Orders/README.md#OrdersDB-Delete"},
+		}},
+	}, orders.Apps)
+	assert.ElementsMatch(t, []OwnerDependency{
+		{From: "Orders", To: "Payments", ToLink: "../Payments/README.md#Payments"},
+		{From: "Orders", To: "Stock", ToLink: "../Stock/README.md#Stock",
+			Owner: "stock@example.com", OwnerLink: "stock-example.com/README.md"},
+	}, orders.Dependencies)
+	assert.Equal(t, "stock@example.com", owners[1].Name)
+	assert.Empty(t, owners[1].Dependencies)
+	require.Len(t, unowned, 1)
+	assert.Equal(t, "Payments", unowned[0].Name)
+
+	b, err := afero.ReadFile(fs, "out/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "[Owners](owners/README.md)")
+	b, err = afero.ReadFile(fs, "out/owners/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "| [Order Team](order-team/README.md) | 2 | orders@example.com |")
+	assert.Contains(t, string(b), "| [Payments](../Payments/README.md#Payments) |")
+	b, err = afero.ReadFile(fs, "out/owners/order-team/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "[Owners](../README.md)")
+	assert.Contains(t, string(b), "| [OrdersDB](../../Orders/README.md#Database-OrdersDB) |")
+	assert.Contains(t, string(b),
+		"| Orders | [Stock](../../Stock/README.md#Stock) | [stock@example.com](../stock-example.com/README.md) |")
+	assert.Contains(t, string(b), "| Orders | [Payments](../../Payments/README.md#Payments) | Unowned |")
+
+	broken, err := CheckLinks(fs, "out")
+	require.NoError(t, err)
+	for _, l := range broken {
+		assert.NotContains(t, l.Page, ownersDir, l.String())
+	}
+}
+
+func TestOwnerKeys(t *testing.T) {
+	t.Parallel()
+
+	p, _ := ownersProject(t)
+	p.WithOwnerKeys("Owner.Email")
+	assert.Equal(t, "orders@example.com", p.OwnerOf(p.RootModule.GetApps()["Orders"]))
+	assert.Equal(t, "", p.OwnerOf(p.RootModule.GetApps()["OrdersDB"]))
+}
+
+func TestCodeOwners(t *testing.T) {
+	t.Parallel()
+
+	p, fs := ownersProject(t)
+	p.WithCodeOwners(true).Run()
+
+	b, err := afero.ReadFile(fs, "out/"+CodeOwnersFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "\n/temp.sysl orders@example.com stock@example.com\n")
+
+	p.WithOwnerKeys("Owner.Team")
+	for value, owner := range map[string]string{
+		"@org/orders":        "@org/orders",
+		"@octocat":           "@octocat",
+		"orders@example.com": "orders@example.com",
+		"Order Team":         "",
+		"@org/order team":    "",
+	} {
+		app := &sysl.Application{Attrs: map[string]*sysl.Attribute{
+			"owner.team": {Attribute: &sysl.Attribute_S{S: value}},
+		}}
+		assert.Equal(t, owner, p.codeOwnerOf(app), value)
+	}
+}
+
+func TestOwnerDirs(t *testing.T) {
+	t.Parallel()
+
+	dirs := ownerDirs([]string{"Order Team", "order-team", "A&B", "A B", "Stock", "チーム", "팀"})
+	assert.Equal(t, "stock", dirs["Stock"])
+	seen := make(map[string]bool)
+	for owner, dir := range dirs {
+		assert.NotEmpty(t, dir, owner)
+		assert.False(t, seen[dir], "%s shares %s", owner, dir)
+		seen[dir] = true
+	}
+	assert.Regexp(t, "^order-team-[0-9a-f]{8}$", dirs["Order Team"])
+}
+
+func TestOwnerPagesDontCollide(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(`
+A:
+    @package = "A"
+    @owner.team = "Order Team"
+B:
+    @package = "B"
+    @owner.team = "order-team"
+C:
+    @package = "C"
+    @owner.team = "チーム"
+`)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out").Run()
+
+	b, err := afero.ReadFile(fs, "out/owners/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "# Owners")
+	pages, err := afero.Glob(fs, "out/owners/*/README.md")
+	require.NoError(t, err)
+	assert.Len(t, pages, 3)
+}
+
+func TestServeCodeOwners(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(ownersSysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "html", logrus.New(), nil, nil, "").
+		WithCodeOwners(true).
+		ServerSettings(false, true, false).
+		Update(m)
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+CodeOwnersFile, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "\n/temp.sysl orders@example.com stock@example.com\n")
+	assert.NotContains(t, rec.Body.String(), "<script")
+}
@@ -0,0 +1,226 @@
+// pubsub.go: events declared with `<->` and the apps that publish and subscribe to them
+package catalog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+// Event is a pub/sub endpoint along with the endpoints that publish it and the apps subscribed to it.
+type Event struct {
+	Name        string
+	Publisher   string // The app that declares the event
+	App         *sysl.Application
+	Endpoint    *sysl.Endpoint
+	PublishedBy []string // "App <- Endpoint" of every endpoint that publishes the event
+	Subscribers []Subscriber
+}
+
+// Subscriber is an endpoint (named "Publisher -> Event") that handles an event.
+type Subscriber struct {
+	App      string
+	Endpoint *sysl.Endpoint
+	Local    bool // The subscriber is part of the module the event was requested for
+}
+
+// IsEvent returns true for pub/sub endpoints (declared with `<->`).
+func IsEvent(e *sysl.Endpoint) bool {
+	return e.GetIsPubsub()
+}
+
+// IsSubscriber returns true for endpoints that handle an event published by another app.
+func IsSubscriber(e *sysl.Endpoint) bool {
+	return e.GetSource() != nil
+}
+
+// IsPubSub returns true for events and their subscribers.
+func IsPubSub(e *sysl.Endpoint) bool {
+	return IsEvent(e) || IsSubscriber(e)
+}
+
+// Events returns the events declared by the apps of m, sorted by app and event name.
+func (p *Generator) Events(m *sysl.Module) []Event {
+	var events []Event
+	for _, appName := range SortedKeys(m.GetApps()) {
+		app := m.GetApps()[appName]
+		if syslutil.HasPattern(app.GetAttrs(), "ignore") {
+			continue
+		}
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			e := app.GetEndpoints()[endpointName]
+			if !IsEvent(e) || syslutil.HasPattern(e.GetAttrs(), "ignore") {
+				continue
+			}
+			events = append(events, Event{
+				Name:        e.GetName(),
+				Publisher:   appName,
+				App:         app,
+				Endpoint:    e,
+				PublishedBy: p.publishedBy(appName, e.GetName()),
+				Subscribers: p.subscribers(m, appName, e.GetName()),
+			})
+		}
+	}
+	return events
+}
+
+// publishedBy returns the endpoints in the root module that call publisher <- event.
+func (p *Generator) publishedBy(publisher, event string) []string {
+	var publishers []string
+	for _, appName := range SortedKeys(p.RootModule.GetApps()) {
+		app := p.RootModule.GetApps()[appName]
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			e := app.GetEndpoints()[endpointName]
+			if IsEvent(e) {
+				continue
+			}
+			found := false
+			forEachCall(e.GetStmt(), func(call *sysl.Call) {
+				if JoinAppNameString(call.GetTarget()) == publisher && call.GetEndpoint() == event {
+					found = true
+				}
+			})
+			if found {
+				publishers = append(publishers, fmt.Sprintf("%s <- %s", appName, endpointName))
+			}
+		}
+	}
+	return publishers
+}
+
+// subscribers returns the endpoints in the root module that subscribe to publisher -> event.
+func (p *Generator) subscribers(m *sysl.Module, publisher, event string) []Subscriber {
+	var subscribers []Subscriber
+	for _, appName := range SortedKeys(p.RootModule.GetApps()) {
+		app := p.RootModule.GetApps()[appName]
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			e := app.GetEndpoints()[endpointName]
+			if !IsSubscriber(e) || JoinAppNameString(e.GetSource()) != publisher {
+				continue
+			}
+			if strings.TrimSpace(strings.TrimPrefix(e.GetName(), publisher+" ->")) != event {
+				continue
+			}
+			_, local := m.GetApps()[appName]
+			subscribers = append(subscribers, Subscriber{App: appName, Endpoint: e, Local: local})
+		}
+	}
+	return subscribers
+}
+
+// forEachCall calls f with every call statement in stmts, including those nested in other statements.
+func forEachCall(stmts []*sysl.Statement, f func(call *sysl.Call)) {
+	forEachStatement(stmts, func(s *sysl.Statement) {
+		if s.GetCall() != nil {
+			f(s.GetCall())
+		}
+	})
+}
+
+// forEachStatement calls f with every statement in stmts, and then with the statements nested in it.
+func forEachStatement(stmts []*sysl.Statement, f func(s *sysl.Statement)) {
+	for _, s := range stmts {
+		f(s)
+		switch {
+		case s.GetCond() != nil:
+			forEachStatement(s.GetCond().GetStmt(), f)
+		case s.GetLoop() != nil:
+			forEachStatement(s.GetLoop().GetStmt(), f)
+		case s.GetLoopN() != nil:
+			forEachStatement(s.GetLoopN().GetStmt(), f)
+		case s.GetForeach() != nil:
+			forEachStatement(s.GetForeach().GetStmt(), f)
+		case s.GetGroup() != nil:
+			forEachStatement(s.GetGroup().GetStmt(), f)
+		case s.GetAlt() != nil:
+			for _, choice := range s.GetAlt().GetChoice() {
+				forEachStatement(choice.GetStmt(), f)
+			}
+		}
+	}
+}
+
+// eventFlow returns the nodes and edges of an event flow diagram for the events of m.
+// Node names are prefixed with "app:" or "event:" so apps and events with the same name don't clash.
+func (p *Generator) eventFlow(m *sysl.Module) (nodes []string, labels map[string]string, edges [][3]string) {
+	labels = make(map[string]string)
+	addNode := func(id, label string) {
+		if _, ok := labels[id]; !ok {
+			labels[id] = label
+			nodes = append(nodes, id)
+		}
+	}
+	for _, event := range p.Events(m) {
+		eventID := "event:" + event.Publisher + "." + event.Name
+		addNode(eventID, event.Name)
+		publishers := []string{event.Publisher}
+		for _, publishedBy := range event.PublishedBy {
+			if app := strings.Split(publishedBy, " <- ")[0]; app != event.Publisher {
+				publishers = append(publishers, app)
+			}
+		}
+		for _, publisher := range publishers {
+			addNode("app:"+publisher, publisher)
+			edges = append(edges, [3]string{"app:" + publisher, eventID, "publishes"})
+		}
+		for _, s := range event.Subscribers {
+			addNode("app:"+s.App, s.App)
+			edges = append(edges, [3]string{eventID, "app:" + s.App, "subscribes"})
+		}
+	}
+	return nodes, labels, edges
+}
+
+// EventFlowMermaid returns a mermaid diagram of the apps that publish and subscribe to the events of m.
+func (p *Generator) EventFlowMermaid(m *sysl.Module) string {
+	nodes, labels, edges := p.eventFlow(m)
+	if len(nodes) == 0 {
+		return ""
+	}
+	ids := make(map[string]string, len(nodes))
+	apps := make(map[string]string)
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for i, n := range nodes {
+		ids[n] = fmt.Sprintf("n%d", i)
+		if strings.HasPrefix(n, "app:") {
+			apps[ids[n]] = labels[n]
+		}
+		if strings.HasPrefix(n, "event:") {
+			fmt.Fprintf(&b, "    %s{{\"%s\"}}\n", ids[n], labels[n])
+		} else {
+			fmt.Fprintf(&b, "    %s[\"%s\"]\n", ids[n], labels[n])
+		}
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "    %s -- %s --> %s\n", ids[e[0]], e[2], ids[e[1]])
+	}
+	return p.themeMermaid(p.styleMermaid(b.String(), apps))
+}
+
+// EventFlowPlantuml returns a plantuml url of a diagram of the apps that publish and subscribe to the events of m.
+func (p *Generator) EventFlowPlantuml(m *sysl.Module) string {
+	nodes, labels, edges := p.eventFlow(m)
+	if len(nodes) == 0 {
+		return ""
+	}
+	ids := make(map[string]string, len(nodes))
+	var b strings.Builder
+	b.WriteString("@startuml\nleft to right direction\n")
+	for i, n := range nodes {
+		ids[n] = fmt.Sprintf("n%d", i)
+		if strings.HasPrefix(n, "event:") {
+			fmt.Fprintf(&b, "queue \"%s\" as %s\n", labels[n], ids[n])
+		} else {
+			fmt.Fprintf(&b, "component \"%s\" as %s%s\n", labels[n], ids[n], plantumlColor(p.appColor(labels[n])))
+		}
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "%s --> %s : %s\n", ids[e[0]], ids[e[1]], e[2])
+	}
+	b.WriteString("@enduml\n")
+	return p.plantumlURL(b.String())
+}
@@ -0,0 +1,53 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pubsubSysl = `
+Orders:
+    @package = "Orders"
+    <-> OrderPlaced(order <: Order):
+        ...
+    PlaceOrder(req <: Order):
+        . <- OrderPlaced
+        return ok <: Order
+    !type Order:
+        id <: int
+
+Shipping:
+    @package = "Fulfilment"
+    Orders -> OrderPlaced:
+        Warehouse <- Reserve
+
+Warehouse:
+    @package = "Fulfilment"
+    Reserve: ...
+`
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	p := NewProject("test", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out")
+
+	orders := p.ModuleAsPackages(m)["Orders"]
+	events := p.Events(orders)
+	require.Len(t, events, 1)
+	assert.Equal(t, "OrderPlaced", events[0].Name)
+	assert.Equal(t, "Orders", events[0].Publisher)
+	assert.Equal(t, []string{"Orders <- PlaceOrder"}, events[0].PublishedBy)
+	require.Len(t, events[0].Subscribers, 1)
+	assert.Equal(t, "Shipping", events[0].Subscribers[0].App)
+	assert.False(t, events[0].Subscribers[0].Local)
+
+	assert.Empty(t, p.Events(p.ModuleAsPackages(m)["Fulfilment"]))
+	assert.Contains(t, p.EventFlowMermaid(orders), "-- subscribes -->")
+}
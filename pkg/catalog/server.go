@@ -11,12 +11,19 @@ import (
 	"strings"
 
 	"github.com/anz-bank/sysl/pkg/sysl"
-	"github.com/anz-bank/sysl/pkg/syslwrapper"
 	"github.com/spf13/afero"
 )
 
 // Update loads another Sysl module into a project and runs
 func (p *Generator) Update(m *sysl.Module, errs ...error) *Generator {
+	unlock := p.lock()
+	defer unlock()
+	return p.update(m, errs...)
+}
+
+// update loads another Sysl module into a project and runs, or clears the rendered pages when rendering
+// lazily.
+func (p *Generator) update(m *sysl.Module, errs ...error) *Generator {
 	//p.Fs = afero.NewMemMapFs()
 	p.errs = []error{}
 	for _, err := range errs {
@@ -30,15 +37,20 @@ func (p *Generator) Update(m *sysl.Module, errs ...error) *Generator {
 
 	if len(p.errs) == 0 {
 		p.RootModule = m
+		p.indexed = false
 		p.GeneratedFiles = make(map[string][]byte)
-		p.Mapper = syslwrapper.MakeAppMapper(m)
-		p.Mapper.IndexTypes()
-		p.Mapper.ConvertTypes()
 		if p.RootModule != nil && len(p.ModuleAsMacroPackage(p.RootModule)) <= 1 && !p.CustomTemplate {
 			p.StartTemplateIndex = 1 // skip the MacroPackageProject
 		} else {
 			p.StartTemplateIndex = 0
 		}
+		if p.lazy() {
+			p.Fs = afero.NewMemMapFs()
+			p.cache.rendered = make(map[string]bool)
+			p.cache.pages = p.lazyPages()
+			p.indexModule()
+			return p
+		}
 		p.Run()
 	}
 
@@ -52,15 +64,33 @@ func (p *Generator) ServerSettings(disableCSS, liveReload, imageTags bool) *Gene
 	p.OutputDir = "/"
 	p.Server = true
 	p.Fs = afero.NewMemMapFs()
+	if p.cache == nil {
+		p.cache = &pageCache{rendered: make(map[string]bool)}
+	}
 	return p
 }
 
-// ServeHTTP is implements the handler interface
+// ServeHTTP is implements the handler interface. Pages are served under the read lock of the server, after
+// rendering them if they're rendered lazily.
 func (p *Generator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request := r.URL.Path
+	if strings.HasSuffix(request, "/") && !strings.HasPrefix(request, apiPrefix) {
+		request += "index.html"
+	}
+	if ext := path.Ext(request); p.lazy() && !strings.HasPrefix(request, apiPrefix) && ext != ".svg" && ext != ".ico" {
+		p.renderPage(strings.TrimPrefix(path.Clean(request), "/"))
+	}
+	unlock := p.rlock()
+	defer unlock()
+	if strings.HasPrefix(request, apiPrefix) {
+		p.ServeAPI(w, r)
+		return
+	}
 	var (
 		bytes []byte
 		file  string
 		err   error
+		errs  = p.errs
 	)
 	defer func() {
 		if _, err := w.Write(bytes); err != nil {
@@ -68,64 +98,59 @@ func (p *Generator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 	defer func() {
-		if len(p.errs) > 0 {
-			bytes = convertToEscapedHTML(fmt.Sprintln(p.errs))
+		if len(errs) > 0 {
+			bytes = convertToEscapedHTML(fmt.Sprintln(errs))
 		}
 	}()
-	request := r.URL.Path
 	if p.RootModule == nil && path.Ext(request) != ".ico" {
 		bytes = convertToHTML(`<img class="blink-image" src="favicon.ico">` + flashing)
 		return
 	}
 	if p.Fs == nil && path.Ext(request) != ".ico" {
-		p.Update(p.RootModule)
+		// Only without ServerSettings, whose lock is a no-op.
+		p.update(p.RootModule)
 	}
 	switch path.Ext(request) {
 	case ".svg":
 		w.Header().Set("Content-Type", "image/svg+xml")
 		unescapedPath, err := url.PathUnescape(request)
 		if err != nil {
-			p.errs = append(p.errs, err)
-		}
-		if svg, ok := p.GeneratedFiles[path.Join(unescapedPath)]; ok {
-			bytes = svg
-			return
-		}
-		p.errs = []error{}
-		if err != nil {
-			p.errs = append(p.errs, err)
+			errs = append(errs, err)
 		}
-		p.GeneratedFiles[path.Join(unescapedPath)] = bytes
+		bytes = p.GeneratedFiles[path.Join(unescapedPath)]
 		return
 	case ".ico":
 		bytes, err = base64.StdEncoding.DecodeString(favicon)
 		if err != nil {
-			p.errs = append(p.errs, err)
+			errs = append(errs, err)
 			p.Log.Info(err)
 		}
 		return
 	case ".yaml", ".yml", ".json", ".sysl":
+		// Generated files (such as JSON Schemas) are served first, then the files in the working directory.
+		if path.Ext(request) == ".json" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		if bytes, err = afero.ReadFile(p.Fs, path.Join(p.OutputDir, request)); err == nil {
+			return
+		}
 		bytes, err = afero.ReadFile(afero.NewOsFs(), strings.TrimPrefix(request, "/"))
 		if err != nil {
 			p.Log.Error(err)
-			return
+			w.WriteHeader(http.StatusNotFound)
+			bytes = []byte(http.StatusText(http.StatusNotFound))
 		}
 		return
-	case "":
-		request += "index.html"
 	}
 	bytes, err = afero.ReadFile(p.Fs, path.Join(p.OutputDir, request))
 	if err != nil {
-		if len(p.errs) > 0 && p.errs[len(p.errs)-1].Error() == err.Error() {
-			return
-		}
-		p.errs = append(p.errs, err)
+		errs = append(errs, err)
 		p.Log.Info(err)
 		return
 	}
 	file = string(bytes)
-	if !p.LiveReload {
-		p.errs = []error{}
+	if !p.LiveReload || path.Ext(request) == "" { // Files such as CODEOWNERS are served as they are
+
 		return
 	}
 	switch p.Format {
@@ -137,7 +162,6 @@ func (p *Generator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	default:
 		bytes = convertToEscapedHTML(file)
 	}
-	p.errs = []error{}
 }
 
 func convertToEscapedHTML(file string) []byte {
@@ -0,0 +1,44 @@
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(jsonSchemaSysl)
+	require.NoError(t, err)
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), nil, nil, "").
+		WithJSONSchema(JSONSchemaApps).
+		ServerSettings(false, false, false).
+		Update(m)
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	// The link on /Orders/README.md is "../schemas/Orders.schema.json#/$defs/Order".
+	assert.Contains(t, get("/Orders/README.md").Body.String(), "[JSON Schema](../schemas/Orders.schema.json#/$defs/Order)")
+	rec := get("/schemas/Orders.schema.json")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &schema))
+	assert.Equal(t, "Orders", schema["title"])
+
+	assert.Equal(t, http.StatusNotFound, get("/go.json").Code)
+	// Files that aren't generated are served from the working directory.
+	rec = get("/test/simple.yaml")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Body.String())
+}
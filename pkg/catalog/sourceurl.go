@@ -0,0 +1,82 @@
+// sourceurl.go: links from the documentation to the source of apps and types in a hosted repository
+package catalog
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+)
+
+// SourceLocation is what a source url template is executed with.
+type SourceLocation struct {
+	File    string // The source file as sysl names it, eg. "github.com/org/repo/specs/a.sysl" or "specs/a.sysl"
+	Path    string // File relative to the prefix the template is configured for
+	Version string // The version of an imported file, "" for local files
+	Line    int
+}
+
+// WithSourceURLs sets the templates of the links to source files, keyed by the prefix of the files they're used
+// for (eg. "github.com/org/repo"); the template of the longest matching prefix is used and "" matches every file.
+// Templates are executed with a SourceLocation, eg. "https://github.com/org/repo/blob/{{or .Version "master"}}/{{.Path}}#L{{.Line}}".
+func (p *Generator) WithSourceURLs(templates map[string]string) *Generator {
+	p.SourceURLs = make(map[string]*template.Template, len(templates))
+	for prefix, text := range templates {
+		tmpl, err := template.New(prefix).Parse(text)
+		if err != nil {
+			p.Log.Error("Error registering source url template:", err)
+			continue
+		}
+		p.SourceURLs[strings.Trim(prefix, "/")] = tmpl
+	}
+	return p
+}
+
+// sourceURL returns the link to a source location from the template configured for its file, or false if there
+// isn't one.
+func (p *Generator) sourceURL(file string, ctx *sysl.SourceContext) (string, bool) {
+	prefixes := make([]string, 0, len(p.SourceURLs))
+	for prefix := range p.SourceURLs {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	file = strings.TrimPrefix(file, "./")
+	for _, prefix := range prefixes {
+		if prefix != "" && file != prefix && !strings.HasPrefix(file, prefix+"/") {
+			continue
+		}
+		loc := SourceLocation{
+			File:    file,
+			Path:    strings.TrimPrefix(strings.TrimPrefix(file, prefix), "/"),
+			Version: ctx.GetVersion(),
+			Line:    sourceLineNumber(ctx),
+		}
+		var b bytes.Buffer
+		if err := p.SourceURLs[prefix].Execute(&b, loc); err != nil {
+			p.Log.Errorf("Error creating source url of %s: %s", file, err)
+			return "", false
+		}
+		return b.String(), true
+	}
+	return "", false
+}
+
+// sourceLineNumber returns the line of a source location counting from 1, or 0 if it doesn't have one (sysl
+// counts lines from 0).
+func sourceLineNumber(ctx *sysl.SourceContext) int {
+	if ctx.GetStart() == nil {
+		return 0
+	}
+	return int(ctx.GetStart().GetLine()) + 1
+}
+
+// sourceLine returns the fragment of the line of a source location in the served source, or "" if there isn't one.
+func sourceLine(ctx *sysl.SourceContext) string {
+	if line := sourceLineNumber(ctx); line > 0 {
+		return fmt.Sprintf("#L%d", line)
+	}
+	return ""
+}
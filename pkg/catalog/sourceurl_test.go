@@ -0,0 +1,41 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourcePath(t *testing.T) {
+	t.Parallel()
+
+	app := func(file, version string, line int32, attrs map[string]*sysl.Attribute) *sysl.Application {
+		return &sysl.Application{
+			Attrs: attrs,
+			SourceContext: &sysl.SourceContext{
+				File: file, Version: version, Start: &sysl.SourceContext_Location{Line: line},
+			},
+		}
+	}
+	p := (&Generator{Log: logrus.New()}).WithSourceURLs(map[string]string{
+		"":                    `https://github.com/org/specs/blob/master/{{.Path}}#L{{.Line}}`,
+		"github.com/org/repo": `https://github.com/org/repo/blob/{{or .Version "master"}}/{{.Path}}#L{{.Line}}`,
+	})
+	assert.Equal(t, "https://github.com/org/specs/blob/master/specs/a.sysl#L3",
+		p.SourcePath(app("specs/a.sysl", "", 2, nil)))
+	assert.Equal(t, "https://github.com/org/repo/blob/v1.2.0/b/c.sysl#L10",
+		p.SourcePath(app("github.com/org/repo/b/c.sysl", "v1.2.0", 9, nil)))
+	assert.Equal(t, "https://github.com/org/specs/blob/master/github.com/org/repository/d.sysl#L1",
+		p.SourcePath(app("github.com/org/repository/d.sysl", "", 0, nil)))
+	assert.Equal(t, "https://github.com/org/specs/blob/master/other.sysl#L2",
+		p.SourcePath(app("specs/a.sysl", "", 1, map[string]*sysl.Attribute{
+			"source_path": {Attribute: &sysl.Attribute_S{S: "other.sysl"}},
+		})))
+
+	p = &Generator{Log: logrus.New()}
+	assert.Equal(t, "/specs/a.sysl", p.SourcePath(app("specs/a.sysl", "", 2, nil)))
+	p.Server = true
+	assert.Equal(t, "/specs/a.sysl#L3", p.SourcePath(app("specs/a.sysl", "", 2, nil)))
+}
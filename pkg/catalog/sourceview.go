@@ -0,0 +1,189 @@
+// sourceview.go: highlighted, line numbered pages of the sysl files that make up the module
+package catalog
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/spf13/afero"
+)
+
+// sourceDir is the directory of the source pages in the output directory.
+const sourceDir = "source"
+
+var syslToken = regexp.MustCompile(`(#.*$)|("(?:[^"\\]|\\.)*")|(@[\w.]+)|(\[~[^\]]*\])` +
+	`|(!(?:type|alias|table|enum|union|view|wrap)\b|\b(?:return|import|as|for|if|else|loop|until|while|alt|one of|sequence of|set of)\b)` +
+	`|(<:|<-|->|\.\.\.)`)
+
+// syslTokenClasses are the css classes of the groups of syslToken.
+var syslTokenClasses = []string{"comment", "string", "annotation", "pattern", "keyword", "operator"}
+
+// WithSourceFs sets the filesystem the sysl files are read from to generate their source pages (which aren't
+// generated without one); files that aren't in it are retrieved with the Retriever.
+func (p *Generator) WithSourceFs(fs afero.Fs) *Generator {
+	p.SourceFs = fs
+	return p
+}
+
+// sourcePage returns the page (relative to OutputDir) of the source of file.
+func sourcePage(file string) string {
+	file = strings.TrimLeft(path.Clean("/"+file), "/")
+	return path.Join(sourceDir, file+".html")
+}
+
+// sourceDecl is a declaration in a sysl file that is documented in the catalog.
+type sourceDecl struct {
+	Name string
+	Link func() string // The link to its documentation from the page being generated, "" if it has none
+}
+
+// syslSource is a sysl file the root module was parsed from.
+type syslSource struct {
+	Version string
+	Decls   map[int][]sourceDecl // By line
+}
+
+// sourceFiles returns the files of every app, endpoint and type of the root module and their declarations.
+func (p *Generator) sourceFiles() map[string]*syslSource {
+	files := make(map[string]*syslSource)
+	pages := p.appPages()
+	add := func(ctx *sysl.SourceContext, name string, link func() string) {
+		file := ctx.GetFile()
+		if file == "" {
+			return
+		}
+		if files[file] == nil {
+			files[file] = &syslSource{Version: ctx.GetVersion(), Decls: make(map[int][]sourceDecl)}
+		}
+		line := sourceLineNumber(ctx)
+		files[file].Decls[line] = append(files[file].Decls[line], sourceDecl{Name: name, Link: link})
+	}
+	for _, appName := range SortedKeys(p.RootModule.GetApps()) {
+		appName, app := appName, p.RootModule.GetApps()[appName]
+		add(app.GetSourceContext(), appName, func() string { return p.appLink(pages, appName) })
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			endpointName := endpointName
+			add(app.GetEndpoints()[endpointName].GetSourceContext(), appName+" "+endpointName, func() string {
+				return p.endpointLink(pages, appName, endpointName)
+			})
+		}
+		for _, typeName := range SortedKeys(app.GetTypes()) {
+			reference := appName + "." + typeName
+			add(app.GetTypes()[typeName].GetSourceContext(), reference, func() string {
+				if doc, ok := p.TypeDocs[reference]; ok {
+					return p.pageLink(doc.Page, doc.Anchor)
+				}
+				return ""
+			})
+		}
+	}
+	return files
+}
+
+// readSource reads a sysl file from SourceFs, or retrieves it (at version) when it's not there.
+func (p *Generator) readSource(file, version string) ([]byte, error) {
+	if b, err := afero.ReadFile(p.SourceFs, file); err == nil || p.Retriever == nil {
+		return b, err
+	}
+	if version != "" {
+		file += "@" + version
+	}
+	b, _, err := p.Retriever.Retrieve(file)
+	return b, err
+}
+
+// CreateSourcePages writes a highlighted page with numbered, linkable lines for every sysl file that the
+// root module was parsed from. Declarations link to their documentation, and SourcePath links to the pages.
+// Only html output has source pages; markdown links to the sysl files themselves, which hosts like GitHub render.
+func (p *Generator) CreateSourcePages() {
+	p.SourcePages = nil
+	if p.RootModule == nil || p.SourceFs == nil || p.Format != "html" {
+		return
+	}
+	currentDir := p.CurrentDir
+	defer func() { p.CurrentDir = currentDir }()
+	p.SourcePages = make(map[string]string)
+	files := p.sourceFiles()
+	for _, file := range SortedKeys(files) {
+		contents, err := p.readSource(file, files[file].Version)
+		if err != nil {
+			p.Log.Warnf("Error reading %s for its source page: %s", file, err)
+			continue
+		}
+		page := sourcePage(file)
+		p.CurrentDir = path.Dir(page)
+		filename := path.Join(p.OutputDir, page)
+		if err := p.Fs.MkdirAll(path.Dir(filename), os.ModePerm); err != nil {
+			p.Log.Error("Error creating source page:", err)
+			continue
+		}
+		out := p.sourceHTML(file, string(contents), files[file].Decls)
+		if err := afero.WriteFile(p.Fs, filename, []byte(out), 0644); err != nil {
+			p.Log.Error("Error creating source page:", err)
+			continue
+		}
+		p.SourcePages[file] = page
+	}
+}
+
+// sourceHTML returns the source page of a sysl file.
+func (p *Generator) sourceHTML(file, contents string, decls map[int][]sourceDecl) string {
+	var b strings.Builder
+	b.WriteString(strings.Replace(header, "<title>Sysl Catalog</title>", "<title>"+html.EscapeString(file)+"</title>", 1))
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<pre class=\"source\">", html.EscapeString(file))
+	lines := strings.Split(strings.TrimSuffix(contents, "\n"), "\n")
+	for i, line := range lines {
+		n := i + 1
+		fmt.Fprintf(&b, `<span class="line" id="L%d"><a class="number" href="#L%d">%d</a>%s`, n, n, n, highlightSysl(line))
+		for _, decl := range decls[n] {
+			if link := decl.Link(); link != "" {
+				fmt.Fprintf(&b, ` <a class="doc" href="%s" title="Documentation of %s">&#x1F4D6;</a>`,
+					html.EscapeString(link), html.EscapeString(decl.Name))
+			}
+		}
+		b.WriteString("</span>\n")
+	}
+	b.WriteString("</pre>\n")
+	b.WriteString(style + sourceStyle + endTags)
+	return b.String()
+}
+
+// highlightSysl returns a line of sysl as html with its comments, strings, annotations, patterns, keywords
+// and operators in spans.
+func highlightSysl(line string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range syslToken.FindAllStringSubmatchIndex(line, -1) {
+		b.WriteString(html.EscapeString(line[last:m[0]]))
+		for g, class := range syslTokenClasses {
+			if m[2*g+2] >= 0 {
+				fmt.Fprintf(&b, `<span class="%s">%s</span>`, class, html.EscapeString(line[m[0]:m[1]]))
+				break
+			}
+		}
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(line[last:]))
+	return b.String()
+}
+
+const sourceStyle = `
+<style type="text/css">
+pre.source { line-height: 1.4; }
+pre.source .line { display: block; }
+pre.source .line:target { background-color: #fff8c5; }
+pre.source .number { display: inline-block; width: 4em; padding-right: 1em; text-align: right; color: #999; text-decoration: none; user-select: none; }
+pre.source .doc { text-decoration: none; }
+pre.source .comment { color: #6a737d; }
+pre.source .string { color: #032f62; }
+pre.source .annotation { color: #6f42c1; }
+pre.source .pattern { color: #e36209; }
+pre.source .keyword { color: #d73a49; font-weight: bold; }
+pre.source .operator { color: #005cc5; }
+</style>
+`
@@ -0,0 +1,48 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSourcePages(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(pubsubSysl)
+	require.NoError(t, err)
+	sourceFs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(sourceFs, "temp.sysl", []byte(pubsubSysl), 0644))
+	fs := afero.NewMemMapFs()
+	p := NewProject("temp.sysl", plantumlService, "html", logrus.New(), m, fs, "out").WithSourceFs(sourceFs)
+	p.Run()
+
+	assert.Equal(t, map[string]string{"temp.sysl": "source/temp.sysl.html"}, p.SourcePages)
+	b, err := afero.ReadFile(fs, "out/source/temp.sysl.html")
+	require.NoError(t, err)
+	page := string(b)
+	assert.Contains(t, page, `<span class="line" id="L2"><a class="number" href="#L2">2</a>Orders: <a class="doc" href="../Orders/index.html#Orders" title="Documentation of Orders">`)
+	assert.Contains(t, page, `<a class="doc" href="../Orders/index.html#Orders.Order" title="Documentation of Orders.Order">`)
+
+	b, err = afero.ReadFile(fs, "out/Orders/index.html")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `href="../source/temp.sysl.html#L2"`)
+}
+
+func TestHighlightSysl(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		`    <span class="annotation">@package</span> = <span class="string">&#34;a # b&#34;</span> <span class="comment"># note</span>`,
+		highlightSysl(`    @package = "a # b" # note`))
+	assert.Equal(t,
+		`    Do(x <span class="operator">&lt;:</span> Order)<span class="pattern">[~rest]</span>:`,
+		highlightSysl(`    Do(x <: Order)[~rest]:`))
+	assert.Equal(t,
+		`        <span class="keyword">return</span> ok <span class="operator">&lt;:</span> <span class="keyword">sequence of</span> Order`,
+		highlightSysl(`        return ok <: sequence of Order`))
+}
@@ -0,0 +1,197 @@
+// stats.go: a dashboard page of counts and rankings of the apps, endpoints and types of the catalog
+package catalog
+
+import (
+	"path"
+	"sort"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+const (
+	statsDir = "stats" // The directory of the statistics page in the output directory
+	statsTop = 10      // The length of the rankings
+)
+
+// Stats is what the statistics page shows.
+type Stats struct {
+	Packages             []PackageStats // In the order of the pages
+	Total                PackageStats
+	FanIn                []Ranked // Apps called by the most apps
+	FanOut               []Ranked // Apps that call the most apps
+	LargestTypes         []Ranked // Types with the most fields
+	ExternalDependencies []Ranked // Apps that call the most apps outside of their package
+	Charts               bool     // Add mermaid charts of the counts
+}
+
+// PackageStats is the number of apps, endpoints, types and databases in a package.
+type PackageStats struct {
+	MacroPackage string // "" when the catalog has no macro packages
+	Package      string
+	Link         string // The page of the package
+	Apps         int
+	Endpoints    int
+	Types        int
+	Databases    int
+}
+
+// Ranked is an app (or type, "App.Type") in a ranking and its count.
+type Ranked struct {
+	Name  string
+	Link  string // Its documentation, "" if it isn't documented
+	Count int
+}
+
+// WithStatsCharts adds mermaid charts of the counts to the statistics page.
+func (p *Generator) WithStatsCharts(charts bool) *Generator {
+	p.StatsCharts = charts
+	return p
+}
+
+// Stats returns the statistics of the apps of m; links are relative to the page being generated.
+func (p *Generator) Stats(m *sysl.Module) Stats {
+	stats := Stats{Charts: p.StatsCharts}
+	pages := p.appPages()
+	macroPackages := map[string]*sysl.Module{"": m}
+	if p.StartTemplateIndex == 0 {
+		macroPackages = p.ModuleAsMacroPackage(m)
+	}
+	packageOf := make(map[string]string)
+	var types []Ranked
+	for _, macroPackageName := range SortedKeys(macroPackages) {
+		packages := p.ModuleAsPackages(macroPackages[macroPackageName])
+		for _, packageName := range SortedKeys(packages) {
+			s := PackageStats{
+				MacroPackage: macroPackageName,
+				Package:      packageName,
+				Link: p.relativeLink(
+					path.Join(macroPackageName, packageName, markdownName(p.OutputFileName, packageName)), ""),
+			}
+			for _, appName := range SortedKeys(packages[packageName].GetApps()) {
+				app := packages[packageName].GetApps()[appName]
+				packageOf[appName] = path.Join(macroPackageName, packageName)
+				s.Apps++
+				s.Endpoints += len(app.GetEndpoints())
+				s.Types += len(app.GetTypes())
+				if syslutil.HasPattern(app.GetAttrs(), "db") {
+					s.Databases++
+				}
+				for typeName, t := range app.GetTypes() {
+					reference := appName + "." + typeName
+					var link string
+					if doc, ok := p.TypeDocs[reference]; ok {
+						link = p.relativeLink(doc.Page, doc.Anchor)
+					}
+					types = append(types, Ranked{Name: reference, Link: link, Count: fieldCount(t)})
+				}
+			}
+			stats.Packages = append(stats.Packages, s)
+			stats.Total.Apps += s.Apps
+			stats.Total.Endpoints += s.Endpoints
+			stats.Total.Types += s.Types
+			stats.Total.Databases += s.Databases
+		}
+	}
+
+	fanIn, fanOut, external := make(map[string]int), make(map[string]int), make(map[string]int)
+	_, deps := p.dependencies(m)
+	for _, dep := range deps {
+		fanIn[dep.To]++
+		fanOut[dep.From]++
+		if to, ok := packageOf[dep.To]; !ok || to != packageOf[dep.From] {
+			external[dep.From]++
+		}
+	}
+	ranked := func(counts map[string]int) []Ranked {
+		var r []Ranked
+		for _, appName := range SortedKeys(counts) {
+			link := p.appLink(pages, appName)
+			if _, ok := p.RootModule.GetApps()[appName]; !ok {
+				link = ""
+			}
+			r = append(r, Ranked{Name: appName, Link: link, Count: counts[appName]})
+		}
+		return topRanked(r)
+	}
+	stats.FanIn = ranked(fanIn)
+	stats.FanOut = ranked(fanOut)
+	stats.ExternalDependencies = ranked(external)
+	stats.LargestTypes = topRanked(types)
+	return stats
+}
+
+// fieldCount returns the number of fields of a tuple or relation type.
+func fieldCount(t *sysl.Type) int {
+	return len(typeFields(t))
+}
+
+// topRanked returns the statsTop highest counts, ties in order of name.
+func topRanked(r []Ranked) []Ranked {
+	sort.SliceStable(r, func(i, j int) bool {
+		if r[i].Count != r[j].Count {
+			return r[i].Count > r[j].Count
+		}
+		return r[i].Name < r[j].Name
+	})
+	if len(r) > statsTop {
+		r = r[:statsTop]
+	}
+	return r
+}
+
+// CreateStatsPage writes the statistics page of the root module to stats/, and returns it (relative to OutputDir).
+func (p *Generator) CreateStatsPage() string {
+	return p.createExtraPage(statsDir, "Statistics", StatsTemplate, func() interface{} { return p })
+}
+
+// StatsTemplate is the statistics page.
+const StatsTemplate = `
+{{/* Automatically generated by https://github.com/anz-bank/sysl-catalog it is strongly recommended not to edit this file */}}
+{{range $name, $link := .Links}} [{{$name}}]({{$link}}) | {{end}}
+# {{.Title}}
+{{$stats := Stats .RootModule}}
+## Packages
+| Macro Package | Package | Applications | Endpoints | Types | Databases |
+|----|----|----|----|----|----|{{range $s := $stats.Packages}}
+| {{$s.MacroPackage}} | [{{$s.Package}}]({{$s.Link}}) | {{$s.Apps}} | {{$s.Endpoints}} | {{$s.Types}} | {{$s.Databases}} |{{end}}
+| **Total** | | {{$stats.Total.Apps}} | {{$stats.Total.Endpoints}} | {{$stats.Total.Types}} | {{$stats.Total.Databases}} |
+{{if and $stats.Charts $stats.Packages}}
+<script src="https://cdn.jsdelivr.net/npm/mermaid/dist/mermaid.min.js"></script>
+
+<pre class="mermaid">
+pie title Applications per package{{range $s := $stats.Packages}}
+    "{{$s.Package}}" : {{$s.Apps}}{{end}}
+</pre>
+
+<pre class="mermaid">
+pie title Endpoints per package{{range $s := $stats.Packages}}
+    "{{$s.Package}}" : {{$s.Endpoints}}{{end}}
+</pre>
+{{end}}
+## Fan-in
+Applications called by the most applications.
+
+| Application | Called by |
+|----|----|{{range $r := $stats.FanIn}}
+| {{if $r.Link}}[{{$r.Name}}]({{$r.Link}}){{else}}{{$r.Name}}{{end}} | {{$r.Count}} |{{end}}
+
+## Fan-out
+Applications that call the most applications.
+
+| Application | Calls |
+|----|----|{{range $r := $stats.FanOut}}
+| {{if $r.Link}}[{{$r.Name}}]({{$r.Link}}){{else}}{{$r.Name}}{{end}} | {{$r.Count}} |{{end}}
+
+## External Dependencies
+Applications that call the most applications outside of their package.
+
+| Application | External dependencies |
+|----|----|{{range $r := $stats.ExternalDependencies}}
+| {{if $r.Link}}[{{$r.Name}}]({{$r.Link}}){{else}}{{$r.Name}}{{end}} | {{$r.Count}} |{{end}}
+
+## Largest Types
+| Type | Fields |
+|----|----|{{range $r := $stats.LargestTypes}}
+| {{if $r.Link}}[{{$r.Name}}]({{$r.Link}}){{else}}{{$r.Name}}{{end}} | {{$r.Count}} |{{end}}
+`
@@ -0,0 +1,120 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const statsSysl = `
+A:
+    @package = "P1"
+    Call:
+        B <- Get
+        C <- Get
+        DB <- Query
+    !type Big:
+        a <: int
+        b <: int
+        c <: int
+    !type Small:
+        a <: int
+B:
+    @package = "P1"
+    Get:
+        C <- Get
+C:
+    @package = "P2"
+    Get: ...
+DB[~db]:
+    @package = "P2"
+    Query: ...
+    !table Row:
+        id <: int [~pk]
+`
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(statsSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out")
+	p.Run()
+
+	p.CurrentDir = statsDir
+	stats := p.Stats(m)
+	assert.Equal(t, []PackageStats{
+		{Package: "P1", Link: "../P1/README.md", Apps: 2, Endpoints: 2, Types: 2},
+		{Package: "P2", Link: "../P2/README.md", Apps: 2, Endpoints: 2, Types: 1, Databases: 1},
+	}, stats.Packages)
+	assert.Equal(t, PackageStats{Apps: 4, Endpoints: 4, Types: 3, Databases: 1}, stats.Total)
+	assert.Equal(t, []Ranked{
+		{Name: "C", Link: "../P2/README.md#C", Count: 2},
+		{Name: "B", Link: "../P1/README.md#B", Count: 1},
+		{Name: "DB", Link: "../P2/README.md#Database-DB", Count: 1},
+	}, stats.FanIn)
+	assert.Equal(t, []Ranked{
+		{Name: "A", Link: "../P1/README.md#A", Count: 3},
+		{Name: "B", Link: "../P1/README.md#B", Count: 1},
+	}, stats.FanOut)
+	assert.Equal(t, []Ranked{
+		{Name: "A", Link: "../P1/README.md#A", Count: 2},
+		{Name: "B", Link: "../P1/README.md#B", Count: 1},
+	}, stats.ExternalDependencies)
+	assert.Equal(t, Ranked{Name: "A.Big", Link: "../P1/README.md#A.Big", Count: 3}, stats.LargestTypes[0])
+
+	b, err := afero.ReadFile(fs, "out/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "[Statistics](stats/README.md)")
+	b, err = afero.ReadFile(fs, "out/stats/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "| **Total** | | 4 | 4 | 3 | 1 |")
+	assert.Contains(t, string(b), "| [A.Big](../P1/README.md#A.Big) | 3 |")
+	assert.NotContains(t, string(b), "pie title")
+
+	broken, err := CheckLinks(fs, "out")
+	require.NoError(t, err)
+	for _, l := range broken {
+		assert.NotEqual(t, "stats/README.md", l.Page, l.String())
+	}
+}
+
+func TestStatsCharts(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(statsSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out").WithStatsCharts(true).Run()
+
+	b, err := afero.ReadFile(fs, "out/stats/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "pie title Applications per package\n    \"P1\" : 2\n    \"P2\" : 2\n")
+}
+
+func TestStatsPackageDir(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(`
+Counter:
+    @package = "stats"
+    Count: ...
+`)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("test.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out")
+	p.Run()
+
+	b, err := afero.ReadFile(fs, "out/stats/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "Counter", "the package page isn't overwritten")
+	assert.NotContains(t, string(b), "# Statistics")
+	b, err = afero.ReadFile(fs, "out/README.md")
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "[Statistics]")
+}
@@ -13,13 +13,37 @@ const ProjectTemplateMermaid = `
 [{{$val}}]({{$val}}/README.md)|{{end}}
 
 ## Integration Diagram
+{{range $view := IntegrationMermaidViews .Module .Title false 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}<pre class="mermaid">
+{{$view.Diagram}}
+</pre>{{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}
+
+## End Point Analysis Integration Diagram
+{{range $view := IntegrationMermaidViews .Module .Title true 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}<pre class="mermaid">
+{{$view.Diagram}}
+</pre>{{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}
+
+## System Context Diagram
 <pre class="mermaid">
-{{IntegrationMermaid .Module .Title false}}
+{{C4ContextMermaid .Module (Base .Title)}}
 </pre>
 
-## End Point Analysis Integration Diagram
+## Container Diagram
 <pre class="mermaid">
-{{IntegrationMermaid .Module .Title true}}
+{{C4ContainerMermaid .Module (Base .Title)}}
 </pre>
 
 `
@@ -28,7 +52,14 @@ const MacroPackageProjectMermaid = `
 <script src="https://cdn.jsdelivr.net/npm/mermaid/dist/mermaid.min.js"></script>
 
 {{/* Automatically generated by https://github.com/anz-bank/sysl-catalog it is strongly recommended not to edit this file */}}
+{{range $name, $link := .Links}} [{{$name}}]({{$link}}) | {{end}} 
 # {{Base .Title}}
+{{$projects := RootProjects}}{{if gt (len $projects) 1}}
+## Projects
+| Project | Packages | Source Location |
+----|----|----{{range $project := $projects}}
+{{$project.Name}} | {{range $i, $pkg := $project.Packages}}{{if $i}}, {{end}}[{{$pkg}}]({{$pkg}}/README.md){{end}} | [{{$project.Source}}]({{$project.Source}})|{{end}}
+{{end}}
 
 | Package |
 ----|{{if .Module}}{{range $val := MacroPackages .Module}}
@@ -36,13 +67,37 @@ const MacroPackageProjectMermaid = `
 
 ## Integration Diagram
 
+{{if .Module}}{{range $view := IntegrationMermaidViews .Module .Title false 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}<pre class="mermaid">
+{{$view.Diagram}}
+</pre>{{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}{{end}}
+
+## End Point Analysis Integration Diagram
+{{if .Module}}{{range $view := IntegrationMermaidViews .Module .Title true 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}<pre class="mermaid">
+{{$view.Diagram}}
+</pre>{{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}{{end}}
+
+## System Context Diagram
 <pre class="mermaid">
-{{if .Module}}{{IntegrationMermaid .Module .Title false}}{{end}}
+{{if .Module}}{{C4ContextMermaid .Module (Base .Title)}}{{end}}
 </pre>
 
-## End Point Analysis Integration Diagram
+## Container Diagram
 <pre class="mermaid">
-{{if .Module}}{{IntegrationMermaid .Module .Title true}}{{end}}
+{{if .Module}}{{C4ContainerMermaid .Module (Base .Title)}}{{end}}
 </pre>
 
 `
@@ -58,7 +113,14 @@ const NewPackageTemplateMermaid = `
 # {{$packageName}}
 
 ## Integration Diagram
-<pre class="mermaid">{{IntegrationMermaid . $packageName false}}</pre>
+{{range $view := IntegrationMermaidViews . $packageName false 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}<pre class="mermaid">{{$view.Diagram}}</pre>{{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}
 
 {{$Apps := .Apps}}
 
@@ -71,27 +133,36 @@ const NewPackageTemplateMermaid = `
 ## Database Index
 | Database Application Name  | Source Location |
 ----|----{{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}{{if and (eq (hasPattern $app.Attrs "ignore") false) (eq (hasPattern $app.Attrs "db") true)}}
-[{{SanitiseOutputName $appName}}](#Database-{{$appName}}) | [{{SourcePath $app}}]({{SourcePath $app}})|  {{end}}{{end}}
+[{{SanitiseOutputName $appName}}](#Database-{{$appName}}){{DeprecationBadge $app}} | [{{SourcePath $app}}]({{SourcePath $app}})|  {{end}}{{end}}
 {{end}}
 
 ## Application Index
 {{$anyApps := false}}
 
-{{$Apps := .Apps}}{{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}{{if eq (hasPattern $app.Attrs "ignore") false}}{{$Endpoints := $app.Endpoints}}{{range $endpointName := SortedKeys $Endpoints}}{{$endpoint := index $Endpoints $endpointName}}{{if eq (hasPattern $endpoint.Attrs "ignore") false}}{{if not $anyApps}}| Application Name | Method | Source Location |
-|----|----|----|{{$anyApps = true}}{{end}}
-| {{$appName}} | [{{$endpoint.Name}}](#{{SanitiseOutputName $appName}}-{{SanitiseOutputName $endpoint.Name}}) | [{{SourcePath $app}}]({{SourcePath $app}})|  {{end}}{{end}}{{end}}{{end}}
+{{$anyEndpoints := false}}
+{{$Apps := .Apps}}{{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}{{if eq (hasPattern $app.Attrs "ignore") false}}{{$Endpoints := $app.Endpoints}}{{range $endpointName := SortedKeys $Endpoints}}{{$endpoint := index $Endpoints $endpointName}}{{if eq (hasPattern $endpoint.Attrs "ignore") false}}{{if IsSubscriber $endpoint}}{{$anyApps = true}}{{else if not (IsEvent $endpoint)}}{{if not $anyEndpoints}}| Application Name | Method | Source Location |
+|----|----|----|{{$anyApps = true}}{{$anyEndpoints = true}}{{end}}
+| {{$appName}}{{DeprecationBadge $app}} | [{{$endpoint.Name}}](#{{SanitiseOutputName $appName}}-{{SanitiseOutputName $endpoint.Name}}){{DeprecationBadge $endpoint}} | [{{SourcePath $app}}]({{SourcePath $app}})|  {{end}}{{end}}{{end}}{{end}}{{end}}
 
 {{if not $anyApps}}
 <span style="color:grey">No Applications Defined</span>
 {{end}}
 
+{{$events := Events .}}
+{{if $events}}
+## Event Index
+| Event | Publisher | Subscribers |
+|----|----|----|{{range $event := $events}}
+| [{{$event.Name}}](#Event-{{SanitiseOutputName $event.Publisher}}-{{SanitiseOutputName $event.Name}}) | {{$event.Publisher}} | {{range $i, $s := $event.Subscribers}}{{if $i}}, {{end}}{{$s.App}}{{end}} |{{end}}
+{{end}}
+
 
 ## Type Index
 {{$anyTypes := false}}
 
 {{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}{{$types := $app.Types}}{{if ne (hasPattern $app.Attrs "db") true}}{{range $typeName := SortedKeys $types}}{{$type := index $types $typeName}}{{if not $anyTypes}}| Application Name | Type Name | Source Location |
 |----|----|----|{{$anyTypes = true}}{{end}}
-| {{$appName}} | [{{$typeName}}](#{{SanitiseOutputName $appName}}.{{SanitiseOutputName $typeName}}) | [{{SourcePath $type}}]({{SourcePath $type}})|{{end}}{{end}}{{end}}
+| {{$appName}} | [{{$typeName}}](#{{SanitiseOutputName $appName}}.{{SanitiseOutputName $typeName}}){{DeprecationBadge $type}} | [{{SourcePath $type}}]({{SourcePath $type}})|{{end}}{{end}}{{end}}
 
 {{if not $anyTypes}}
 <span style="color:grey">No Types Defined</span>
@@ -116,6 +187,45 @@ const NewPackageTemplateMermaid = `
 {{end}}
 
 
+{{if $events}}
+# Events
+
+## Event Flow Diagram
+<pre class="mermaid">
+{{EventFlowMermaid .}}
+</pre>
+{{range $event := $events}}
+
+## <a name=Event-{{SanitiseOutputName $event.Publisher}}-{{SanitiseOutputName $event.Name}}></a>Event {{$event.Publisher}} {{$event.Name}}
+{{Attribute $event.Endpoint "description"}}
+
+Publisher: {{$event.Publisher}}
+{{range $publishedBy := $event.PublishedBy}}
+- Published by {{$publishedBy}}{{end}}
+
+{{if $event.Subscribers}}
+| Subscriber | Handler |
+|----|----|{{range $s := $event.Subscribers}}
+| {{$s.App}} | {{if $s.Local}}[{{$s.Endpoint.Name}}](#{{SanitiseOutputName $s.App}}-{{SanitiseOutputName $s.Endpoint.Name}}){{else}}{{$s.Endpoint.Name}}{{end}} |{{end}}
+{{else}}
+<span style="color:grey">No Subscribers</span>
+{{end}}
+
+<details>
+<summary>Payload types</summary>
+
+{{range $param := $event.Endpoint.Param}}
+{{Attribute $param.Type "description"}}
+
+{{DataModelAliasTable $event.App $param}}
+{{else}}
+<span style="color:grey">No Payload types</span>
+{{end}}
+</details>
+{{end}}
+{{end}}
+
+
 {{if $anyApps}}
 # Applications
 {{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}
@@ -123,7 +233,7 @@ const NewPackageTemplateMermaid = `
 {{if eq (hasPattern $app.Attrs "db") false}}
 {{if ne (len $app.Endpoints) 0}}
 
-## Application {{$appName}}
+## <a name={{SanitiseOutputName $appName}}></a>Application {{$appName}}{{DeprecationBadge $app}}
 
 {{$desc := Attribute $app "description"}}
 {{if $desc}}
@@ -137,10 +247,10 @@ const NewPackageTemplateMermaid = `
 {{end}}
 
 {{range $e := $app.Endpoints}}
-{{if eq (hasPattern $e.Attrs "ignore") false}}
+{{if and (eq (hasPattern $e.Attrs "ignore") false) (not (IsEvent $e))}}
 
 
-### <a name={{SanitiseOutputName $appName}}-{{SanitiseOutputName $e.Name}}></a>{{$appName}} {{$e.Name}}
+### <a name={{SanitiseOutputName $appName}}-{{SanitiseOutputName $e.Name}}></a>{{$appName}} {{$e.Name}}{{DeprecationBadge $e}}
 {{Attribute $e "description"}}
 
 <details>
@@ -165,6 +275,13 @@ const NewPackageTemplateMermaid = `
 {{Attribute $param.Type "description"}}
 
 {{DataModelAliasTable $app $param}}
+{{with ExampleParamJSON $app $param}}
+<details>
+<summary>Example</summary>
+
+{{CodeBlock "json" .}}
+</details>
+{{end}}
 
 {{end}}
 
@@ -199,6 +316,13 @@ const NewPackageTemplateMermaid = `
 {{Attribute $ret "description"}}{{end}}
 
 {{$diagram}}
+{{with ExampleReturnJSON $appName $s}}
+<details>
+<summary>Example</summary>
+
+{{CodeBlock "json" .}}
+</details>
+{{end}}
 
 {{end}}{{end}}
 
@@ -224,14 +348,23 @@ const NewPackageTemplateMermaid = `
 {{range $typeName := SortedKeys $types}}{{$type := index $types $typeName}}
 <a name={{SanitiseOutputName $appName}}.{{SanitiseOutputName $typeName}}></a>
 
-### {{$appName}}.{{$typeName}}
+### {{$appName}}.{{$typeName}}{{DeprecationBadge $type}}
 {{$typedesc := (Attribute $type "description")}}
 {{if ne $typedesc ""}}- {{$typedesc}}{{end}}
+{{with JSONSchemaLink $appName $typeName}}
+[JSON Schema]({{.}})
+{{end}}
 
 {{if DataModelTable $appName $typeName ""}}
 #### Fields
 {{DataModelTable $appName $typeName ""}}
 {{end}}
+{{with UsedBy $appName $typeName}}
+#### Used by
+| Used by | As |
+|----|----|{{range $use := .}}
+| {{if $use.Link}}[{{$use.Name}}]({{$use.Link}}){{else}}{{$use.Name}}{{end}} | {{$use.Kind}} |{{end}}
+{{end}}
 
 {{end}}{{end}}{{end}}
 {{end}}
@@ -11,26 +11,73 @@ const ProjectTemplate = `
 [{{$val}}]({{$val}}/README.md)|{{end}}
 
 ## Integration Diagram
-<img src="{{IntegrationPlantuml .Module .Title false}}">
+{{range $view := IntegrationPlantumlViews .Module .Title false 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}<img src="{{$view.Diagram}}">{{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}
 
 ## End Point Analysis Integration Diagram
-<img src="{{IntegrationPlantuml .Module .Title true}}">
+{{range $view := IntegrationPlantumlViews .Module .Title true 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}<img src="{{$view.Diagram}}">{{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}
+
+## System Context Diagram
+<img src="{{C4ContextPlantuml .Module (Base .Title)}}">
+
+## Container Diagram
+<img src="{{C4ContainerPlantuml .Module (Base .Title)}}">
 
 `
 
 const MacroPackageProject = `
 {{/* Automatically generated by https://github.com/anz-bank/sysl-catalog it is strongly recommended not to edit this file */}}
+{{range $name, $link := .Links}} [{{$name}}]({{$link}}) | {{end}} 
 # {{Base .Title}}
+{{$projects := RootProjects}}{{if gt (len $projects) 1}}
+## Projects
+| Project | Packages | Source Location |
+----|----|----{{range $project := $projects}}
+{{$project.Name}} | {{range $i, $pkg := $project.Packages}}{{if $i}}, {{end}}[{{$pkg}}]({{$pkg}}/README.md){{end}} | [{{$project.Source}}]({{$project.Source}})|{{end}}
+{{end}}
 
 | Package |
 ----|{{if .Module}}{{range $val := MacroPackages .Module}}
 [{{$val}}]({{$val}}/README.md)|{{end}}{{end}}
 
 ## Integration Diagram
-<img src="{{if .Module}}{{IntegrationPlantuml .Module .Title false}}{{end}}">
+{{if .Module}}{{range $view := IntegrationPlantumlViews .Module .Title false 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}<img src="{{$view.Diagram}}">{{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}{{end}}
 
 ## End Point Analysis Integration Diagram
-<img src="{{if .Module}}{{IntegrationPlantuml .Module .Title true}}{{end}}">
+{{if .Module}}{{range $view := IntegrationPlantumlViews .Module .Title true 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}<img src="{{$view.Diagram}}">{{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}{{end}}
+
+## System Context Diagram
+<img src="{{if .Module}}{{C4ContextPlantuml .Module (Base .Title)}}{{end}}">
+
+## Container Diagram
+<img src="{{if .Module}}{{C4ContainerPlantuml .Module (Base .Title)}}{{end}}">
 
 `
 
@@ -42,7 +89,14 @@ const NewPackageTemplate = `
 # {{$packageName}}
 
 ## Integration Diagram
-![]({{IntegrationPlantuml . $packageName false}})
+{{range $view := IntegrationPlantumlViews . $packageName false 30 60}}
+{{if $view.Title}}#### {{$view.Title}}{{end}}
+{{if $view.Diagram}}![]({{$view.Diagram}}){{else}}
+| From | To |
+|----|----|{{range $dep := $view.Dependencies}}
+| {{$dep.From}} | {{$dep.To}} |{{end}}
+{{end}}
+{{end}}
 {{$Apps := .Apps}}
 
 {{$databases := false}}
@@ -54,27 +108,36 @@ const NewPackageTemplate = `
 ## Database Index
 | Database Application Name  | Source Location |
 ----|----{{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}{{if and (eq (hasPattern $app.Attrs "ignore") false) (eq (hasPattern $app.Attrs "db") true)}}
-[{{SanitiseOutputName $appName}}](#Database-{{$appName}}) | [{{SourcePath $app}}]({{SourcePath $app}})|  {{end}}{{end}}
+[{{SanitiseOutputName $appName}}](#Database-{{$appName}}){{DeprecationBadge $app}} | [{{SourcePath $app}}]({{SourcePath $app}})|  {{end}}{{end}}
 {{end}}
 
 ## Application Index
 {{$anyApps := false}}
 
-{{$Apps := .Apps}}{{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}{{if eq (hasPattern $app.Attrs "ignore") false}}{{$Endpoints := $app.Endpoints}}{{range $endpointName := SortedKeys $Endpoints}}{{$endpoint := index $Endpoints $endpointName}}{{if eq (hasPattern $endpoint.Attrs "ignore") false}}{{if not $anyApps}}| Application Name | Method | Source Location |
-|----|----|----|{{$anyApps = true}}{{end}}
-| {{$appName}} | [{{$endpoint.Name}}](#{{SanitiseOutputName $appName}}-{{SanitiseOutputName $endpoint.Name}}) | [{{SourcePath $app}}]({{SourcePath $app}})|  {{end}}{{end}}{{end}}{{end}}
+{{$anyEndpoints := false}}
+{{$Apps := .Apps}}{{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}{{if eq (hasPattern $app.Attrs "ignore") false}}{{$Endpoints := $app.Endpoints}}{{range $endpointName := SortedKeys $Endpoints}}{{$endpoint := index $Endpoints $endpointName}}{{if eq (hasPattern $endpoint.Attrs "ignore") false}}{{if IsSubscriber $endpoint}}{{$anyApps = true}}{{else if not (IsEvent $endpoint)}}{{if not $anyEndpoints}}| Application Name | Method | Source Location |
+|----|----|----|{{$anyApps = true}}{{$anyEndpoints = true}}{{end}}
+| {{$appName}}{{DeprecationBadge $app}} | [{{$endpoint.Name}}](#{{SanitiseOutputName $appName}}-{{SanitiseOutputName $endpoint.Name}}){{DeprecationBadge $endpoint}} | [{{SourcePath $app}}]({{SourcePath $app}})|  {{end}}{{end}}{{end}}{{end}}{{end}}
 
 {{if not $anyApps}}
 <span style="color:grey">No Applications Defined</span>
 {{end}}
 
+{{$events := Events .}}
+{{if $events}}
+## Event Index
+| Event | Publisher | Subscribers |
+|----|----|----|{{range $event := $events}}
+| [{{$event.Name}}](#Event-{{SanitiseOutputName $event.Publisher}}-{{SanitiseOutputName $event.Name}}) | {{$event.Publisher}} | {{range $i, $s := $event.Subscribers}}{{if $i}}, {{end}}{{$s.App}}{{end}} |{{end}}
+{{end}}
+
 
 ## Type Index
 {{$anyTypes := false}}
 
 {{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}{{$types := $app.Types}}{{if ne (hasPattern $app.Attrs "db") true}}{{range $typeName := SortedKeys $types}}{{$type := index $types $typeName}}{{if not $anyTypes}}| Application Name | Type Name | Source Location |
 |----|----|----|{{$anyTypes = true}}{{end}}
-| {{$appName}} | [{{$typeName}}](#{{SanitiseOutputName $appName}}.{{SanitiseOutputName $typeName}}) | [{{SourcePath $type}}]({{SourcePath $type}})|{{end}}{{end}}{{end}}
+| {{$appName}} | [{{$typeName}}](#{{SanitiseOutputName $appName}}.{{SanitiseOutputName $typeName}}){{DeprecationBadge $type}} | [{{SourcePath $type}}]({{SourcePath $type}})|{{end}}{{end}}{{end}}
 
 {{if not $anyTypes}}
 <span style="color:grey">No Types Defined</span>
@@ -96,6 +159,43 @@ const NewPackageTemplate = `
 {{end}}
 
 
+{{if $events}}
+# Events
+
+## Event Flow Diagram
+![]({{EventFlowPlantuml .}})
+{{range $event := $events}}
+
+## <a name=Event-{{SanitiseOutputName $event.Publisher}}-{{SanitiseOutputName $event.Name}}></a>Event {{$event.Publisher}} {{$event.Name}}
+{{Attribute $event.Endpoint "description"}}
+
+Publisher: {{$event.Publisher}}
+{{range $publishedBy := $event.PublishedBy}}
+- Published by {{$publishedBy}}{{end}}
+
+{{if $event.Subscribers}}
+| Subscriber | Handler |
+|----|----|{{range $s := $event.Subscribers}}
+| {{$s.App}} | {{if $s.Local}}[{{$s.Endpoint.Name}}](#{{SanitiseOutputName $s.App}}-{{SanitiseOutputName $s.Endpoint.Name}}){{else}}{{$s.Endpoint.Name}}{{end}} |{{end}}
+{{else}}
+<span style="color:grey">No Subscribers</span>
+{{end}}
+
+<details>
+<summary>Payload types</summary>
+
+{{range $param := $event.Endpoint.Param}}
+{{Attribute $param.Type "description"}}
+
+{{DataModelAliasTable $event.App $param}}
+{{else}}
+<span style="color:grey">No Payload types</span>
+{{end}}
+</details>
+{{end}}
+{{end}}
+
+
 {{if $anyApps}}
 # Applications
 {{range $appName := SortedKeys .Apps}}{{$app := index $Apps $appName}}
@@ -103,7 +203,7 @@ const NewPackageTemplate = `
 {{if eq (hasPattern $app.Attrs "db") false}}
 {{if ne (len $app.Endpoints) 0}}
 
-## Application {{$appName}}
+## <a name={{SanitiseOutputName $appName}}></a>Application {{$appName}}{{DeprecationBadge $app}}
 
 {{$desc := Attribute $app "description"}}
 {{if $desc}}
@@ -117,10 +217,10 @@ const NewPackageTemplate = `
 {{end}}
 
 {{range $e := $app.Endpoints}}
-{{if eq (hasPattern $e.Attrs "ignore") false}}
+{{if and (eq (hasPattern $e.Attrs "ignore") false) (not (IsEvent $e))}}
 
 
-### <a name={{SanitiseOutputName $appName}}-{{SanitiseOutputName $e.Name}}></a>{{$appName}} {{$e.Name}}
+### <a name={{SanitiseOutputName $appName}}-{{SanitiseOutputName $e.Name}}></a>{{$appName}} {{$e.Name}}{{DeprecationBadge $e}}
 {{Attribute $e "description"}}
 
 <details>
@@ -143,6 +243,13 @@ const NewPackageTemplate = `
 {{Attribute $param.Type "description"}}
 
 ![]({{DataModelParamPlantuml $app $param}})
+{{with ExampleParamJSON $app $param}}
+<details>
+<summary>Example</summary>
+
+{{CodeBlock "json" .}}
+</details>
+{{end}}
 {{end}}
 
 {{if $e.RestParams}}{{if $e.RestParams.UrlParam}}
@@ -174,6 +281,13 @@ const NewPackageTemplate = `
 {{Attribute $ret "description"}}{{end}}
 
 ![]({{$diagram}})
+{{with ExampleReturnJSON $appName $s}}
+<details>
+<summary>Example</summary>
+
+{{CodeBlock "json" .}}
+</details>
+{{end}}
 
 {{end}}{{end}}
 
@@ -200,20 +314,26 @@ const NewPackageTemplate = `
 <a name={{SanitiseOutputName $appName}}.{{SanitiseOutputName $typeName}}></a><details>
 <summary>{{$appName}}.{{$typeName}}</summary>
 
-### {{$appName}}.{{$typeName}}
+### {{$appName}}.{{$typeName}}{{DeprecationBadge $type}}
 {{$typedesc := (Attribute $type "description")}}
 {{if ne $typedesc ""}}- {{$typedesc}}{{end}}
 
 ![]({{DataModelPlantuml $appName $typeName $type false}})
 
-[Full Diagram]({{DataModelPlantuml $appName $typeName $type true}})
+[Full Diagram]({{DataModelPlantuml $appName $typeName $type true}}){{with JSONSchemaLink $appName $typeName}} | [JSON Schema]({{.}}){{end}}
 
 {{if Fields $type}}
 #### Fields
 {{$fieldHeader := false}}
 {{$fieldMap := Fields $type}}{{range $fieldName := SortedKeys $fieldMap}}{{$field := index $fieldMap $fieldName}}{{if not $fieldHeader}}| Field name | Type | Description |
 |----|----|----|{{$fieldHeader = true}}{{end}}
-| {{$fieldName}} | {{FieldType $field}} | {{$desc := Attribute $field "description"}}{{if ne $desc $typedesc}}{{$desc}}{{end}}|{{end}}
+| {{$fieldName}}{{DeprecationBadge $field}} | {{FieldTypeLink $appName $field}} | {{$desc := Attribute $field "description"}}{{if ne $desc $typedesc}}{{$desc}}{{end}}|{{end}}
+{{end}}
+{{with UsedBy $appName $typeName}}
+#### Used by
+| Used by | As |
+|----|----|{{range $use := .}}
+| {{if $use.Link}}[{{$use.Name}}]({{$use.Link}}){{else}}{{$use.Name}}{{end}} | {{$use.Kind}} |{{end}}
 {{end}}
 
 </details>{{end}}{{end}}{{end}}
@@ -0,0 +1,133 @@
+// theme.go: applies the diagram theme (skinparams, colors by pattern, mermaid theme variables) to every diagram
+package catalog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/anz-bank/sysl-catalog/pkg/catalogdiagrams"
+)
+
+var plantumlParticipant = regexp.MustCompile(
+	`^(actor|boundary|collections|control|database|entity|participant|queue) "(.+)" as (_\d+)$`)
+
+// WithTheme sets the style of the diagrams.
+func (p *Generator) WithTheme(theme catalogdiagrams.Theme) *Generator {
+	p.Theme = theme
+	return p
+}
+
+// patterns returns the patterns of a in the order they're declared.
+func patterns(a Attr) []string {
+	var patterns []string
+	for _, elt := range a.GetAttrs()["patterns"].GetA().GetElt() {
+		patterns = append(patterns, elt.GetS())
+	}
+	return patterns
+}
+
+// appColor returns the color of the first pattern of an app that the theme has a color for,
+// DeprecatedColor if it's deprecated, or "".
+func (p *Generator) appColor(appName string) string {
+	app, ok := p.RootModule.GetApps()[appName]
+	if !ok {
+		return ""
+	}
+	return p.attrColor(app)
+}
+
+// typeColor returns the color of the first pattern of a type ("App.Type") that the theme has a color for,
+// DeprecatedColor if it's deprecated, or "".
+func (p *Generator) typeColor(reference string) string {
+	parts := strings.SplitN(reference, ".", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	t, ok := p.RootModule.GetApps()[parts[0]].GetTypes()[parts[1]]
+	if !ok {
+		return ""
+	}
+	return p.attrColor(t)
+}
+
+// attrColor returns the color of deprecated elements (the theme color of the deprecated pattern, or
+// DeprecatedColor) if a is deprecated, so they stand out whatever their other patterns are. Otherwise it returns the
+// color of the first pattern of a that the theme has a color for, or "".
+func (p *Generator) attrColor(a Attr) string {
+	if Deprecated(a) {
+		if color := p.Theme.Color([]string{"deprecated"}); color != "" {
+			return color
+		}
+		return DeprecatedColor
+	}
+	return p.Theme.Color(patterns(a))
+}
+
+// plantumlColor returns the suffix that colors a plantuml element, which comes after its link.
+func plantumlColor(color string) string {
+	if color == "" {
+		return ""
+	}
+	return " #" + strings.TrimPrefix(color, "#")
+}
+
+// plantumlURL applies the header and skinparams of the theme to a plantuml diagram and returns its url.
+func (p *Generator) plantumlURL(plantumlString string) string {
+	return PlantUMLURL(p.PlantumlService, p.Theme.Plantuml(plantumlString))
+}
+
+// colorParticipants colors the participants (apps) of a plantuml sequence diagram by their patterns.
+func (p *Generator) colorParticipants(plantumlString string) string {
+	lines := strings.Split(plantumlString, "\n")
+	for i, line := range lines {
+		if match := plantumlParticipant.FindStringSubmatch(line); match != nil {
+			lines[i] = line + plantumlColor(p.appColor(match[2]))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// themeMermaid adds the init directive of the theme to a mermaid diagram.
+func (p *Generator) themeMermaid(mermaidString string) string {
+	if mermaidString == "" {
+		return ""
+	}
+	return p.Theme.MermaidInit() + mermaidString
+}
+
+// styleMermaid appends style directives to a mermaid flowchart that fill the nodes of apps (ids mapped to
+// app names) with the colors of their patterns.
+func (p *Generator) styleMermaid(mermaidString string, apps map[string]string) string {
+	var b strings.Builder
+	for _, id := range SortedKeys(apps) {
+		if color := p.appColor(apps[id]); color != "" {
+			fmt.Fprintf(&b, "    style %s fill:%s\n", id, color)
+		}
+	}
+	if b.Len() == 0 {
+		return mermaidString
+	}
+	return strings.TrimRight(mermaidString, "\n") + "\n" + b.String()
+}
+
+// mermaidAppNodes returns the nodes of apps in a mermaid integration diagram, keyed by node id.
+func (p *Generator) mermaidAppNodes(mermaidString string) map[string]string {
+	ids := make(map[string]string)
+	for appName := range p.RootModule.GetApps() {
+		ids[strings.ReplaceAll(appName, " ", "_")] = appName
+	}
+	apps := make(map[string]string)
+	for _, line := range strings.Split(mermaidString, "\n") {
+		for _, side := range strings.Split(line, "-->") {
+			id := strings.TrimSpace(side)
+			if j := strings.Index(id, "["); j >= 0 {
+				id = id[:j]
+			}
+			if appName, ok := ids[id]; ok {
+				apps[id] = appName
+			}
+		}
+	}
+	return apps
+}
@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anz-bank/sysl-catalog/pkg/catalogdiagrams"
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const themeSysl = `
+Orders:
+    @package = "Orders"
+    PlaceOrder:
+        Stock <- Reserve
+    !type Order[~deprecated]:
+        id <: int
+Stock[~db]:
+    @package = "Orders"
+    Reserve: ...
+`
+
+func themeProject(t *testing.T) *Generator {
+	m, err := parse.NewParser().ParseString(themeSysl)
+	require.NoError(t, err)
+	return NewProject("test", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out").
+		WithTheme(catalogdiagrams.Theme{
+			Header:     "!theme plain",
+			Font:       "Inter",
+			FontSize:   12,
+			Skinparams: map[string]string{"shadowing": "false"},
+			Patterns:   map[string]string{"db": "#B3E5FC", "deprecated": "LightGray"},
+			Mermaid: catalogdiagrams.MermaidTheme{
+				Theme:     "neutral",
+				Variables: map[string]string{"primaryColor": "#FFFFFF"},
+			},
+		})
+}
+
+func TestThemePlantuml(t *testing.T) {
+	t.Parallel()
+
+	p := themeProject(t)
+	assert.Equal(t, `@startuml
+!theme plain
+A -> B
+skinparam defaultFontName Inter
+skinparam defaultFontSize 12
+skinparam shadowing false
+@enduml
+`, p.Theme.Plantuml("@startuml\nA -> B\n@enduml\n"))
+	assert.Equal(t, "@startuml\nA -> B\n@enduml\n", catalogdiagrams.Theme{}.Plantuml("@startuml\nA -> B\n@enduml\n"))
+
+	assert.Equal(t, `@startuml
+[Stock] as _0 [[Orders/README.md#Database-Stock]] #B3E5FC
+state "Stock" as X_0 [[Orders/README.md#Database-Stock]] #B3E5FC {
+}
+@enduml`, p.linkPlantuml(p.appPages(), `@startuml
+[Stock] as _0
+state "Stock" as X_0 {
+}
+@enduml`))
+	assert.Equal(t, "control \"Orders\" as _0\ndatabase \"Stock\" as _1 #B3E5FC",
+		p.colorParticipants("control \"Orders\" as _0\ndatabase \"Stock\" as _1"))
+	assert.Equal(t, `class "Order" as _0 << (D,orchid) Order >> #LightGray {`,
+		p.linkDataModel("Orders", `class "Order" as _0 << (D,orchid) Order >> {`))
+
+	p.Theme.EntityColor, p.Theme.EntityHeader = "#FFAA00", "T"
+	diagram := catalogdiagrams.GenerateThemedDataModel("Orders",
+		catalogdiagrams.FromSyslTypeMap("Orders", p.RootModule.GetApps()["Orders"].GetTypes()), p.Theme)
+	assert.Contains(t, diagram, `class "Order" as _0 << (T,#FFAA00) Order >> {`)
+}
+
+func TestThemeMermaid(t *testing.T) {
+	t.Parallel()
+
+	p := themeProject(t)
+	init := `%%{init: {"theme":"neutral","themeVariables":{"fontFamily":"Inter","primaryColor":"#FFFFFF"}}}%%` + "\n"
+	assert.Equal(t, init, p.Theme.MermaidInit())
+	assert.Equal(t, "", catalogdiagrams.Theme{}.MermaidInit())
+
+	diagram := p.IntegrationMermaid(p.RootModule, "test", false)
+	assert.True(t, strings.HasPrefix(diagram, init))
+	assert.Contains(t, diagram, "    style Stock fill:#B3E5FC\n")
+	assert.NotContains(t, diagram, "style Orders")
+	assert.True(t, strings.HasPrefix(p.SequenceMermaid("Orders", p.RootModule.GetApps()["Orders"].GetEndpoints()["PlaceOrder"]), init))
+}
+
+func TestDeprecatedColor(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(`
+Archive[~db, ~deprecated]:
+    @package = "Orders"
+    Get: ...
+Stock[~db]:
+    @package = "Orders"
+    Reserve: ...
+`)
+	require.NoError(t, err)
+	p := NewProject("test", plantumlService, "markdown", logrus.New(), m, afero.NewMemMapFs(), "out").
+		WithTheme(catalogdiagrams.Theme{Patterns: map[string]string{"db": "#B3E5FC"}})
+	assert.Equal(t, DeprecatedColor, p.appColor("Archive"), "deprecation wins over the other patterns")
+	assert.Equal(t, "#B3E5FC", p.appColor("Stock"))
+
+	p.WithTheme(catalogdiagrams.Theme{Patterns: map[string]string{"db": "#B3E5FC", "deprecated": "Gray"}})
+	assert.Equal(t, "Gray", p.appColor("Archive"))
+}
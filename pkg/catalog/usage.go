@@ -0,0 +1,191 @@
+// usage.go: where each type is used (params, returns, fields and tables) and the types that aren't used
+package catalog
+
+import (
+	"strings"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/anz-bank/sysl/pkg/syslutil"
+)
+
+const unusedDir = "unused" // The directory of the unused types page in the output directory
+
+// typeUse is a use of a type in the module, with its documentation relative to OutputDir.
+type typeUse struct {
+	Kind   string // Parameter, Return, Field or Table
+	Name   string // "App.Endpoint" for parameters and returns, "App.Type.field" for fields
+	Page   string // "" if it isn't documented
+	Anchor string
+}
+
+// TypeUse is a use of a type and a link to it, relative to the page being generated.
+type TypeUse struct {
+	Kind string
+	Name string
+	Link string
+}
+
+// typeReferences returns the types ("App.Type") a sysl type of app refers to.
+func typeReferences(appName string, t *sysl.Type) []string {
+	switch {
+	case t.GetSequence() != nil:
+		return typeReferences(appName, t.GetSequence())
+	case t.GetSet() != nil:
+		return typeReferences(appName, t.GetSet())
+	case t.GetList() != nil:
+		return typeReferences(appName, t.GetList().GetType())
+	case t.GetMap() != nil:
+		return append(typeReferences(appName, t.GetMap().GetKey()), typeReferences(appName, t.GetMap().GetValue())...)
+	case t.GetTypeRef() != nil:
+		refAppName, typeName := GetAppTypeName(&sysl.Param{Type: t})
+		if refAppName == "primitive" || typeName == "" {
+			return nil
+		}
+		if refAppName == "" {
+			refAppName = appName
+		}
+		return []string{refAppName + "." + typeName}
+	}
+	return nil
+}
+
+// returnReference returns the type ("App.Type") returned by a return statement of app, or "".
+func returnReference(appName string, ret *sysl.Return) string {
+	t := strings.TrimSpace(strings.ReplaceAll(ofTypeSymbol.FindString(ret.GetPayload()), "<: ", ""))
+	t = strings.TrimPrefix(t, "sequence of ")
+	if t == "" {
+		return ""
+	}
+	if !strings.Contains(t, ".") {
+		t = appName + "." + t
+	}
+	return t
+}
+
+// typeUses returns the uses of every type of the root module, keyed by "App.Type".
+func (p *Generator) typeUses() map[string][]typeUse {
+	uses := make(map[string][]typeUse)
+	seen := make(map[string]map[typeUse]bool)
+	use := func(reference string, u typeUse) {
+		appName, typeName := splitReference(reference)
+		if _, ok := p.RootModule.GetApps()[appName].GetTypes()[typeName]; !ok {
+			return
+		}
+		if seen[reference] == nil {
+			seen[reference] = make(map[typeUse]bool)
+		}
+		if !seen[reference][u] {
+			seen[reference][u] = true
+			uses[reference] = append(uses[reference], u)
+		}
+	}
+	pages := p.appPages()
+	for _, appName := range SortedKeys(p.RootModule.GetApps()) {
+		app := p.RootModule.GetApps()[appName]
+		if syslutil.HasPattern(app.GetAttrs(), "ignore") {
+			continue
+		}
+		for _, endpointName := range SortedKeys(app.GetEndpoints()) {
+			endpoint := app.GetEndpoints()[endpointName]
+			if syslutil.HasPattern(endpoint.GetAttrs(), "ignore") {
+				continue
+			}
+			u := typeUse{Name: appName + "." + endpointName}
+			if page, ok := pages[appName]; ok {
+				u.Page, u.Anchor = page, p.endpointAnchor(appName, endpointName)
+			}
+			var params []Typer
+			for _, param := range endpoint.GetParam() {
+				params = append(params, param)
+			}
+			for _, param := range endpoint.GetRestParams().GetQueryParam() {
+				params = append(params, param)
+			}
+			for _, param := range endpoint.GetRestParams().GetUrlParam() {
+				params = append(params, param)
+			}
+			u.Kind = "Parameter"
+			for _, param := range params {
+				for _, reference := range typeReferences(appName, param.GetType()) {
+					use(reference, u)
+				}
+			}
+			u.Kind = "Return"
+			forEachStatement(endpoint.GetStmt(), func(s *sysl.Statement) {
+				if s.GetRet() != nil {
+					use(returnReference(appName, s.GetRet()), u)
+				}
+			})
+		}
+		kind := "Field"
+		if syslutil.HasPattern(app.GetAttrs(), "db") {
+			kind = "Table"
+		}
+		for _, typeName := range SortedKeys(app.GetTypes()) {
+			reference := appName + "." + typeName
+			fields := typeFields(app.GetTypes()[typeName])
+			for _, fieldName := range SortedKeys(fields) {
+				u := typeUse{Kind: kind, Name: reference + "." + fieldName}
+				if doc, ok := p.TypeDocs[reference]; ok {
+					u.Page, u.Anchor = doc.Page, doc.Anchor
+				}
+				for _, used := range typeReferences(appName, fields[fieldName]) {
+					if used != reference {
+						use(used, u)
+					}
+				}
+			}
+		}
+	}
+	return uses
+}
+
+// UsedBy returns the uses of a type by the endpoints and other types of the root module.
+func (p *Generator) UsedBy(appName, typeName string) []TypeUse {
+	var uses []TypeUse
+	for _, u := range p.TypeUses[appName+"."+typeName] {
+		use := TypeUse{Kind: u.Kind, Name: u.Name}
+		if u.Page != "" {
+			use.Link = p.anchorLink(u.Page, u.Anchor)
+		}
+		uses = append(uses, use)
+	}
+	return uses
+}
+
+// UnusedTypes returns the documented types, other than database tables, that nothing uses.
+func (p *Generator) UnusedTypes() []Ranked {
+	var unused []Ranked
+	for _, reference := range SortedKeys(p.TypeDocs) {
+		appName, _ := splitReference(reference)
+		if syslutil.HasPattern(p.RootModule.GetApps()[appName].GetAttrs(), "db") || len(p.TypeUses[reference]) > 0 {
+			continue
+		}
+		doc := p.TypeDocs[reference]
+		unused = append(unused, Ranked{Name: reference, Link: p.anchorLink(doc.Page, doc.Anchor)})
+	}
+	return unused
+}
+
+// CreateUnusedTypesPage writes the page of unused types to unused/, and returns it (relative to OutputDir),
+// or "" if every type is used.
+func (p *Generator) CreateUnusedTypesPage() string {
+	return p.createExtraPage(unusedDir, "Unused Types", UnusedTypesTemplate, func() interface{} {
+		if len(p.UnusedTypes()) == 0 {
+			return nil
+		}
+		return p
+	})
+}
+
+// UnusedTypesTemplate is the page of unused types.
+const UnusedTypesTemplate = `
+{{/* Automatically generated by https://github.com/anz-bank/sysl-catalog it is strongly recommended not to edit this file */}}
+{{range $name, $link := .Links}} [{{$name}}]({{$link}}) | {{end}}
+# {{.Title}}
+Types that no endpoint parameter, return statement, field or table uses, which may be candidates for cleanup.
+
+| Type |
+|----|{{range $t := UnusedTypes}}
+| [{{$t.Name}}]({{$t.Link}}) |{{end}}
+`
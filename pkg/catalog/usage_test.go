@@ -0,0 +1,73 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/anz-bank/sysl/pkg/parse"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const usageSysl = `
+Orders:
+    @package = "Orders"
+    PlaceOrder(order <: Order):
+        if valid:
+            return ok <: Receipt
+    ListOrders:
+        return ok <: sequence of Order
+    !type Order:
+        lines <: sequence of Line
+        parent <: Order
+    !type Line:
+        sku <: string
+    !type Receipt:
+        id <: int
+    !type Unused:
+        id <: int
+OrdersDB[~db]:
+    @package = "Orders"
+    !table Customer:
+        id <: int [~pk]
+    !table Purchase:
+        id <: int [~pk]
+        customer_id <: Customer.id [~fk]
+`
+
+func TestUsedBy(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(usageSysl)
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	p := NewProject("temp.sysl", plantumlService, "markdown", logrus.New(), m, fs, "out")
+	p.Run()
+
+	p.CurrentDir = "Orders"
+	assert.Equal(t, []TypeUse{
+		{Kind: "Return", Name: "Orders.ListOrders", Link: "#Orders-ListOrders"},
+		{Kind: "Parameter", Name: "Orders.PlaceOrder", Link: "#Orders-PlaceOrder"},
+	}, p.UsedBy("Orders", "Order"))
+	assert.Equal(t, []TypeUse{{Kind: "Field", Name: "Orders.Order.lines", Link: "#Orders.Order"}},
+		p.UsedBy("Orders", "Line"))
+	assert.Equal(t, []TypeUse{{Kind: "Return", Name: "Orders.PlaceOrder", Link: "#Orders-PlaceOrder"}},
+		p.UsedBy("Orders", "Receipt"))
+	assert.Equal(t, []TypeUse{{Kind: "Table", Name: "OrdersDB.Purchase.customer_id", Link: "#Database-OrdersDB"}},
+		p.UsedBy("OrdersDB", "Customer"))
+	assert.Empty(t, p.UsedBy("Orders", "Unused"))
+
+	p.CurrentDir = unusedDir
+	assert.Equal(t, []Ranked{{Name: "Orders.Unused", Link: "../Orders/README.md#Orders.Unused"}}, p.UnusedTypes())
+
+	b, err := afero.ReadFile(fs, "out/Orders/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "#### Used by\n| Used by | As |\n|----|----|\n| [Orders.Order.lines](#Orders.Order) | Field |")
+	b, err = afero.ReadFile(fs, "out/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "[Unused Types](unused/README.md)")
+	b, err = afero.ReadFile(fs, "out/unused/README.md")
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "| [Orders.Unused](../Orders/README.md#Orders.Unused) |")
+}
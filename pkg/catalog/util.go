@@ -2,12 +2,16 @@
 package catalog
 
 import (
+	"encoding/base64"
 	"fmt"
+	"path"
 	"reflect"
 	"sort"
 	"strings"
 
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/anz-bank/protoc-gen-sysl/newsysl"
 	"github.com/anz-bank/sysl/pkg/cmdutils"
@@ -89,15 +93,23 @@ func Attribute(a Attr, query string) string {
 	return ""
 }
 
+// DefaultMetadataKeys are the attributes printed by ServiceMetadata.
+var DefaultMetadataKeys = []string{
+	"Repo.URL",
+	"Owner.Email",
+	"Owner.Slack",
+	"Server.Prod.URL",
+	"Server.UAT.URL",
+	"Lifecycle",
+}
+
+// ServiceMetadata prints the DefaultMetadataKeys attributes of a.
 func ServiceMetadata(a Attr) string {
-	queries := []string{
-		"Repo.URL",
-		"Owner.Email",
-		"Owner.Slack",
-		"Server.Prod.URL",
-		"Server.UAT.URL",
-		"Lifecycle",
-	}
+	return ServiceMetadataKeys(a, DefaultMetadataKeys...)
+}
+
+// ServiceMetadataKeys prints the attributes of a that match queries (case insensitively), in the order of queries.
+func ServiceMetadataKeys(a Attr, queries ...string) string {
 	queryMap := make(map[string]string)
 	for _, q := range queries {
 		queryMap[strings.ToLower(q)] = ""
@@ -216,6 +228,11 @@ func PlantUMLURL(plantumlService, contents string) string {
 	return fmt.Sprint(plantumlService, "/", "svg", "/~1", encoded)
 }
 
+// MermaidURL returns the url of the SVG of a mermaid diagram rendered by a mermaid.ink service.
+func MermaidURL(mermaidService, contents string) string {
+	return fmt.Sprint(strings.TrimSuffix(mermaidService, "/"), "/svg/", base64.URLEncoding.EncodeToString([]byte(contents)))
+}
+
 // CreateSequenceDiagram creates an sequence diagram and returns the sequence diagram string and any errors
 func CreateSequenceDiagram(m *sysl.Module, call string) (string, error) {
 	l := &cmdutils.Labeler{}
@@ -250,6 +267,54 @@ func GetAppTypeName(param Typer) (appName string, typeName string) {
 	return appName, typeName
 }
 
+// Input formats of sysl modules.
+const (
+	FormatSysl   = "sysl"
+	FormatPb     = "pb"
+	FormatTextPb = "textpb"
+	FormatJson   = "json"
+)
+
+// InputFormat returns the format of a sysl module file based on its extension.
+func InputFormat(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".pb":
+		return FormatPb
+	case ".textpb", ".pbtxt":
+		return FormatTextPb
+	case ".json":
+		return FormatJson
+	default:
+		return FormatSysl
+	}
+}
+
+// UnmarshallModule unmarshalls a compiled sysl module in the pb, textpb or json format.
+func UnmarshallModule(b []byte, format string) (*sysl.Module, error) {
+	m := &sysl.Module{}
+	var err error
+	switch format {
+	case FormatPb:
+		// `sysl pb` writes the text format unless asked otherwise, so .pb files are often text.
+		if err = proto.Unmarshal(b, m); err != nil {
+			m.Reset()
+			if prototext.Unmarshal(b, m) == nil {
+				err = nil
+			}
+		}
+	case FormatTextPb:
+		err = prototext.Unmarshal(b, m)
+	case FormatJson:
+		err = UnmarshallJson(b, m)
+	default:
+		return nil, fmt.Errorf("%s is not a compiled sysl module format", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Unmarshall Json unmarshalls json bytes into a sysl module
 func UnmarshallJson(b []byte, m *sysl.Module) error {
 	if m == nil {
@@ -1,12 +1,15 @@
 package catalog
 
 import (
+	"io/ioutil"
 	"strings"
 	"testing"
 
 	"github.com/anz-bank/sysl/pkg/parse"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestServiceMetadata(t *testing.T) {
@@ -71,6 +74,22 @@ ree:
 	}
 }
 
+func TestServiceMetadataKeys(t *testing.T) {
+	t.Parallel()
+
+	m, err := parse.NewParser().ParseString(`
+foo:
+    @Owner.Email = "1"
+    @Team = "2"
+    @Lifecycle = "3"
+`,
+	)
+	require.NoError(t, err)
+	p := &Generator{MetadataKeys: []string{"team", "Owner.Email"}}
+	assert.Equal(t, "team: 2\n\nOwner.Email: 1\n\n", p.ServiceMetadata(m.GetApps()["foo"]))
+	assert.Equal(t, "Owner.Email: 1\n\nLifecycle: 3\n\n", (&Generator{}).ServiceMetadata(m.GetApps()["foo"]))
+}
+
 func TestSimpleName_Simple(t *testing.T) {
 	t.Parallel()
 
@@ -114,3 +133,39 @@ Foo :: Bar:
 	require.NoError(t, err)
 	assert.Equal(t, "Qux", ModulePackageName(m))
 }
+
+func TestInputFormat(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, FormatSysl, InputFormat("specs/project.sysl"))
+	assert.Equal(t, FormatPb, InputFormat("module.pb"))
+	assert.Equal(t, FormatTextPb, InputFormat("module.textpb"))
+	assert.Equal(t, FormatJson, InputFormat("module.JSON"))
+}
+
+func TestUnmarshallModule(t *testing.T) {
+	t.Parallel()
+
+	b, err := ioutil.ReadFile("../../tests/rest.json")
+	require.NoError(t, err)
+	expected, err := UnmarshallModule(b, FormatJson)
+	require.NoError(t, err)
+
+	binary, err := proto.Marshal(expected)
+	require.NoError(t, err)
+	text, err := prototext.Marshal(expected)
+	require.NoError(t, err)
+
+	for format, b := range map[string][]byte{FormatPb: binary, FormatTextPb: text} {
+		actual, err := UnmarshallModule(b, format)
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(expected, actual), format)
+	}
+	// Text written to a .pb file
+	actual, err := UnmarshallModule(text, FormatPb)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(expected, actual))
+
+	_, err = UnmarshallModule(b, FormatSysl)
+	assert.Error(t, err)
+}
@@ -20,6 +20,7 @@ const tupleArrow = `*--`
 
 type DataModelView struct {
 	datamodeldiagram.DataModelView
+	Theme Theme
 }
 
 type DataModelParam struct {
@@ -36,6 +37,8 @@ func (v *DataModelView) GenerateDataView(dataParam *DataModelParam, appName stri
 	v.StringBuilder.WriteString(integrationdiagram.PumlHeader)
 	//typeMap := map[string]*sysl.Type{}
 
+	entityColor, entityHeader := v.Theme.Entity()
+
 	ignoredTypes := map[string]struct{}{}
 	// TODO: Actually put The appName/project name and the appName in a struct so strings.split and join dont need to be used
 	entityNames := []string{}
@@ -52,16 +55,16 @@ func (v *DataModelView) GenerateDataView(dataParam *DataModelParam, appName stri
 		if relEntity := entityType.GetRelation(); relEntity != nil {
 			isRelation = true
 			viewParam := datamodeldiagram.EntityViewParam{
-				EntityColor:  `orchid`,
-				EntityHeader: `D`,
+				EntityColor:  entityColor,
+				EntityHeader: entityHeader,
 				EntityName:   entityName,
 			}
 			v.DrawRelation(viewParam, relEntity, relationshipMap)
 		} else if tupEntity := entityType.GetTuple(); tupEntity != nil {
 			isRelation = false
 			viewParam := datamodeldiagram.EntityViewParam{
-				EntityColor:  `orchid`,
-				EntityHeader: `D`,
+				EntityColor:  entityColor,
+				EntityHeader: entityHeader,
 				EntityName:   entityName,
 				EntityAlias:  tMap[entityName].alias,
 				IgnoredTypes: ignoredTypes,
@@ -71,8 +74,8 @@ func (v *DataModelView) GenerateDataView(dataParam *DataModelParam, appName stri
 		} else if pe := entityType.GetPrimitive(); pe != sysl.Type_NO_Primitive && len(strings.TrimSpace(pe.String())) > 0 {
 			isRelation = false
 			viewParam := datamodeldiagram.EntityViewParam{
-				EntityColor:  `orchid`,
-				EntityHeader: `D`,
+				EntityColor:  entityColor,
+				EntityHeader: entityHeader,
 				EntityName:   entityName,
 				IgnoredTypes: ignoredTypes,
 				Types:        typeMap,
@@ -84,11 +87,11 @@ func (v *DataModelView) GenerateDataView(dataParam *DataModelParam, appName stri
 			if len(strings.Split(entityName, ".")) == 1 {
 				entityName = appName + entityName
 			}
-			v.StringBuilder.WriteString(fmt.Sprintf("class \"%s\" as %s<< (D,orchid) >> {\n}\n", entityName,
-				v.UniqueVarForAppName("", entityName)))
+			v.StringBuilder.WriteString(fmt.Sprintf("class \"%s\" as %s<< (%s,%s) >> {\n}\n", entityName,
+				v.UniqueVarForAppName("", entityName), entityHeader, entityColor))
 		} else if pe := entityType.GetEnum(); pe != nil {
-			v.StringBuilder.WriteString(fmt.Sprintf("class \"%s enum\" as %s<< (D,orchid) >> {\n}\n", entityName,
-				v.UniqueVarForAppName("", entityName)))
+			v.StringBuilder.WriteString(fmt.Sprintf("class \"%s enum\" as %s<< (%s,%s) >> {\n}\n", entityName,
+				v.UniqueVarForAppName("", entityName), entityHeader, entityColor))
 		}
 	}
 	if isRelation {
@@ -274,6 +277,12 @@ func TypeFromRef(mod *sysl.Module, appName string, t *TypeData) (string, string,
 
 // GenerateDataModel takes all the types in parentAppName and generates data model diagrams for it
 func GenerateDataModel(parentAppName string, t TypeMap) string {
+	return GenerateThemedDataModel(parentAppName, t, Theme{})
+}
+
+// GenerateThemedDataModel generates a data model diagram of the types in parentAppName with the entity
+// color and header of theme.
+func GenerateThemedDataModel(parentAppName string, t TypeMap, theme Theme) string {
 	type datamodelCmd struct {
 		diagrams.Plantumlmixin
 		cmdutils.CmdContextParamDatagen
@@ -288,6 +297,7 @@ func GenerateDataModel(parentAppName string, t TypeMap) string {
 	v := datamodeldiagram.MakeDataModelView(spclass, dataParam.Mod, &stringBuilder, dataParam.Title, "")
 	vNew := &DataModelView{
 		DataModelView: *v,
+		Theme:         theme,
 	}
 	return vNew.GenerateDataView(dataParam, parentAppName, t)
 }
@@ -0,0 +1,112 @@
+package catalogdiagrams
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Default style of the classes of data model diagrams.
+const (
+	DefaultEntityColor  = "orchid"
+	DefaultEntityHeader = "D"
+)
+
+// Theme is the style applied to every PlantUML and Mermaid diagram.
+type Theme struct {
+	Header       string            `json:"header,omitempty"`       // PlantUML added after @startuml, eg. "!theme plain"
+	Font         string            `json:"font,omitempty"`         // Font of every diagram
+	FontSize     int               `json:"fontSize,omitempty"`     // Font size of PlantUML diagrams
+	Skinparams   map[string]string `json:"skinparams,omitempty"`   // PlantUML skinparams, eg. {"shadowing": "false"}
+	Patterns     map[string]string `json:"patterns,omitempty"`     // Colors of apps and types by pattern, eg. {"db": "#B3E5FC"}
+	EntityColor  string            `json:"entityColor,omitempty"`  // Color of the spot of data model classes
+	EntityHeader string            `json:"entityHeader,omitempty"` // Letter in the spot of data model classes
+	Mermaid      MermaidTheme      `json:"mermaid,omitempty"`
+}
+
+// MermaidTheme is the theme and theme variables of Mermaid diagrams.
+type MermaidTheme struct {
+	Theme     string            `json:"theme,omitempty"`     // "default", "base", "dark", "forest" or "neutral"
+	Variables map[string]string `json:"variables,omitempty"` // Theme variables, eg. {"primaryColor": "#B3E5FC"}
+}
+
+// Entity returns the color and letter of the spot of data model classes.
+func (t Theme) Entity() (color, header string) {
+	color, header = t.EntityColor, t.EntityHeader
+	if color == "" {
+		color = DefaultEntityColor
+	}
+	if header == "" {
+		header = DefaultEntityHeader
+	}
+	return color, header
+}
+
+// Color returns the color of the first of patterns that has one, or "" if none of them do.
+func (t Theme) Color(patterns []string) string {
+	for _, pattern := range patterns {
+		if color, ok := t.Patterns[pattern]; ok && color != "" {
+			return color
+		}
+	}
+	return ""
+}
+
+// Plantuml adds the header after @startuml and the font and skinparams before @enduml, where they override
+// the skinparams of the diagram.
+func (t Theme) Plantuml(plantumlString string) string {
+	if t.Header != "" {
+		if i := strings.Index(plantumlString, "@startuml\n"); i >= 0 {
+			i += len("@startuml\n")
+			plantumlString = plantumlString[:i] + strings.TrimSuffix(t.Header, "\n") + "\n" + plantumlString[i:]
+		}
+	}
+	var b strings.Builder
+	if t.Font != "" {
+		fmt.Fprintf(&b, "skinparam defaultFontName %s\n", t.Font)
+	}
+	if t.FontSize > 0 {
+		fmt.Fprintf(&b, "skinparam defaultFontSize %d\n", t.FontSize)
+	}
+	for _, key := range sortedKeys(t.Skinparams) {
+		fmt.Fprintf(&b, "skinparam %s %s\n", key, t.Skinparams[key])
+	}
+	if i := strings.LastIndex(plantumlString, "@enduml"); b.Len() > 0 && i >= 0 {
+		plantumlString = plantumlString[:i] + b.String() + plantumlString[i:]
+	}
+	return plantumlString
+}
+
+// MermaidInit returns the init directive that applies the theme to a Mermaid diagram, or "" if there's nothing
+// to apply.
+func (t Theme) MermaidInit() string {
+	variables := make(map[string]string, len(t.Mermaid.Variables)+1)
+	if t.Font != "" {
+		variables["fontFamily"] = t.Font
+	}
+	for key, value := range t.Mermaid.Variables {
+		variables[key] = value
+	}
+	init := make(map[string]interface{})
+	if t.Mermaid.Theme != "" {
+		init["theme"] = t.Mermaid.Theme
+	}
+	if len(variables) > 0 {
+		init["themeVariables"] = variables
+	}
+	if len(init) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(init) // Maps are marshalled with sorted keys
+	return fmt.Sprintf("%%%%{init: %s}%%%%\n", b)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+// config.go: project configuration files (.sysl-catalog.yaml) for the run command
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/anz-bank/sysl-catalog/pkg/catalogdiagrams"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// FileName is the name of the configuration file that is searched for above the input.
+const FileName = ".sysl-catalog.yaml"
+
+// Config declares everything needed to reproduce a documentation build.
+// Any flag passed on the command line overrides the value declared here.
+type Config struct {
+	Inputs         []string              `json:"inputs,omitempty"`         // Sysl files to generate documentation for
+	InputFormat    string                `json:"inputFormat,omitempty"`    // "auto", "sysl", "pb", "textpb" or "json"
+	Output         string                `json:"output,omitempty"`         // Directory to generate to
+	Type           string                `json:"type,omitempty"`           // "markdown", "html", "document" or "confluence"
+	Templates      []string              `json:"templates,omitempty"`      // Template files, or "mermaid"/"plantuml" for defaults
+	Exports        []string              `json:"exports,omitempty"`        // Architecture exports: "dot" and/or "structurizr"
+	OutputFileName string                `json:"outputFileName,omitempty"` // Output file name for pages; {{.Title}}
+	PlantUML       string                `json:"plantuml,omitempty"`       // PlantUML service to use
+	BaseURL        string                `json:"baseURL,omitempty"`        // URL the output is published at
+	MermaidService string                `json:"mermaidService,omitempty"` // mermaid.ink service for confluence diagrams
+	SourceURLs     map[string]string     `json:"sourceURLs,omitempty"`     // Templates of links to source files by file prefix
+	NoCSS          bool                  `json:"noCSS,omitempty"`          // Disable adding css to html
+	CheckLinks     bool                  `json:"checkLinks,omitempty"`     // Check the links in the output after generating it
+	StatsCharts    bool                  `json:"statsCharts,omitempty"`    // Add mermaid charts to the statistics page
+	OwnerKeys      []string              `json:"ownerKeys,omitempty"`      // Attributes that name the owner of an app
+	CodeOwners     bool                  `json:"codeOwners,omitempty"`     // Write a CODEOWNERS mapping of sysl files to owners
+	JSONSchema     string                `json:"jsonSchema,omitempty"`     // Write JSON Schemas per "app" or per "type"
+	FilterPackage  []string              `json:"filterPackage,omitempty"`  // Regex terms removed from package names
+	FilterRegexps  []*regexp.Regexp      `json:"-"`                        // FilterPackage, compiled by Load
+	MetadataKeys   []string              `json:"metadataKeys,omitempty"`   // Attributes printed by ServiceMetadata
+	Theme          catalogdiagrams.Theme `json:"theme,omitempty"`          // Style of the diagrams
+	Server         Server                `json:"server,omitempty"`
+}
+
+// Server holds the settings used by --serve.
+type Server struct {
+	Port              string   `json:"port,omitempty"`
+	DisableLiveReload bool     `json:"disableLiveReload,omitempty"`
+	WatchIgnore       []string `json:"watchIgnore,omitempty"` // Glob patterns of files whose changes are ignored
+	Lazy              bool     `json:"lazy,omitempty"`        // Render pages when they're first requested
+}
+
+// Find searches dir and each of its parents for FileName and returns the path of the first one found.
+// An empty string is returned if there is no configuration file.
+func Find(fs afero.Fs, dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, FileName)
+		if _, err := fs.Stat(candidate); err == nil {
+			return candidate, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads a configuration file. Relative input, output and template paths are resolved against
+// the directory of the file so that builds don't depend on where sysl-catalog is run from.
+func Load(fs afero.Fs, filename string) (*Config, error) {
+	b, err := afero.ReadFile(fs, filename)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", filename)
+	}
+	dir := filepath.Dir(filename)
+	for i, in := range c.Inputs {
+		c.Inputs[i] = resolve(dir, in)
+	}
+	if c.Output != "" {
+		c.Output = resolve(dir, c.Output)
+	}
+	for i, t := range c.Templates {
+		if isTemplateFile(t) {
+			c.Templates[i] = resolve(dir, t)
+		}
+	}
+	if !oneOf(c.Type, "", "md", "markdown", "html", "document", "confluence") {
+		return nil, errors.Errorf("invalid type %q in %s", c.Type, filename)
+	}
+	if !oneOf(c.JSONSchema, "", "app", "type") {
+		return nil, errors.Errorf("invalid jsonSchema %q in %s", c.JSONSchema, filename)
+	}
+	for _, pattern := range c.FilterPackage {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid filterPackage pattern %q in %s", pattern, filename)
+		}
+		c.FilterRegexps = append(c.FilterRegexps, re)
+	}
+	return c, nil
+}
+
+// oneOf returns whether s is one of values.
+func oneOf(s string, values ...string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// isTemplateFile returns false for the builtin template names and for remote templates.
+func isTemplateFile(t string) bool {
+	switch t {
+	case "", "mermaid", "plantuml":
+		return false
+	}
+	return !strings.Contains(t, "@")
+}
+
+// resolve joins p onto dir and, where possible, makes the result relative to the working directory
+// (the sysl parser resolves imports relative to the root it's given).
+func resolve(dir, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	joined := filepath.Join(dir, p)
+	wd, err := os.Getwd()
+	if err != nil {
+		return joined
+	}
+	if !filepath.IsAbs(joined) {
+		return joined
+	}
+	if rel, err := filepath.Rel(wd, joined); err == nil {
+		return rel
+	}
+	return joined
+}
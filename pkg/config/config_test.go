@@ -0,0 +1,102 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+inputs:
+  - specs/project.sysl
+output: docs
+type: html
+templates:
+  - mermaid
+filterPackage:
+  - "^Org :: "
+metadataKeys:
+  - Owner.Email
+theme:
+  font: Inter
+  patterns:
+    db: "#B3E5FC"
+  mermaid:
+    theme: neutral
+server:
+  port: ":8080"
+`
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	root, err := filepath.Abs("/repo")
+	require.NoError(t, err)
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, filepath.Join(root, FileName), []byte(testConfig), 0644))
+	require.NoError(t, fs.MkdirAll(filepath.Join(root, "specs", "nested"), 0755))
+
+	found, err := Find(fs, filepath.Join(root, "specs", "nested"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, FileName), found)
+}
+
+func TestFindNone(t *testing.T) {
+	t.Parallel()
+
+	found, err := Find(afero.NewMemMapFs(), "/somewhere/else")
+	require.NoError(t, err)
+	assert.Equal(t, "", found)
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, filepath.Join("repo", FileName), []byte(testConfig), 0644))
+
+	c, err := Load(fs, filepath.Join("repo", FileName))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join("repo", "specs", "project.sysl")}, c.Inputs)
+	assert.Equal(t, filepath.Join("repo", "docs"), c.Output)
+	assert.Equal(t, "html", c.Type)
+	assert.Equal(t, []string{"mermaid"}, c.Templates)
+	assert.Equal(t, []string{"^Org :: "}, c.FilterPackage)
+	require.Len(t, c.FilterRegexps, 1)
+	assert.Equal(t, "Orders", c.FilterRegexps[0].ReplaceAllString("Org :: Orders", ""))
+	assert.Equal(t, []string{"Owner.Email"}, c.MetadataKeys)
+	assert.Equal(t, "Inter", c.Theme.Font)
+	assert.Equal(t, map[string]string{"db": "#B3E5FC"}, c.Theme.Patterns)
+	assert.Equal(t, "neutral", c.Theme.Mermaid.Theme)
+	assert.Equal(t, ":8080", c.Server.Port)
+}
+
+func TestLoadInvalidFilterPackage(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, FileName, []byte("filterPackage:\n  - \"^Org (\"\n"), 0644))
+
+	_, err := Load(fs, FileName)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid filterPackage pattern "^Org ("`)
+}
+
+func TestLoadInvalidValues(t *testing.T) {
+	t.Parallel()
+
+	for config, message := range map[string]string{
+		"type: pdf\n":         `invalid type "pdf"`,
+		"jsonSchema: field\n": `invalid jsonSchema "field"`,
+	} {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, FileName, []byte(config), 0644))
+
+		_, err := Load(fs, FileName)
+		require.Error(t, err, config)
+		assert.Contains(t, err.Error(), message)
+	}
+}
@@ -0,0 +1,280 @@
+// Package parsecache keeps the sysl modules parsed from sysl files on disk, so unchanged specifications aren't
+// parsed (and their imports retrieved) again. Entries are keyed by the absolute path of the input and the version
+// of the parser, and an entry is only used while the hashes of the local files it was parsed from still match.
+package parsecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anz-bank/gop/pkg/gop"
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"google.golang.org/protobuf/proto"
+)
+
+// formatVersion changes whenever the cached files change, so older entries are ignored.
+const formatVersion = "1"
+
+// ModulesFile is the file that pins the versions of imports, which is a dependency of every module.
+const ModulesFile = "sysl_modules.yaml"
+
+// MaxEntries is how many modules are kept; the least recently used ones are removed when more are cached.
+const MaxEntries = 256
+
+// Cache is a directory of parsed modules.
+type Cache struct {
+	fs  afero.Fs // Where the sysl files are read from and the cache is kept
+	dir string
+}
+
+// dependency is a file a module was parsed from.
+type dependency struct {
+	File    string `json:"file"`
+	Version string `json:"version,omitempty"` // The version an import was retrieved at, whose contents are fixed
+	Hash    string `json:"hash,omitempty"`    // The hash of a local file, "" if it doesn't exist
+}
+
+// manifest is what a module was parsed from, which is written alongside it.
+type manifest struct {
+	Input        string       `json:"input"`
+	Dependencies []dependency `json:"dependencies"`
+}
+
+// New returns the cache kept in dir.
+func New(fs afero.Fs, dir string) *Cache {
+	return &Cache{fs: fs, dir: dir}
+}
+
+// DefaultDir returns the directory of the cache in the cache directory of the user.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sysl-catalog", "modules"), nil
+}
+
+// Get returns the module parsed from filename, or false if it hasn't been cached or one of the files it was
+// parsed from changed since.
+func (c *Cache) Get(filename string) (*sysl.Module, bool) {
+	key, err := c.key(filename)
+	if err != nil {
+		return nil, false
+	}
+	b, err := afero.ReadFile(c.fs, filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var cached manifest
+	if err := json.Unmarshal(b, &cached); err != nil || cached.Input != filename {
+		return nil, false
+	}
+	for _, dep := range cached.Dependencies {
+		if dep.Version == "" && c.hash(dep.File) != dep.Hash {
+			return nil, false
+		}
+	}
+	b, err = afero.ReadFile(c.fs, filepath.Join(c.dir, key+".pb"))
+	if err != nil {
+		return nil, false
+	}
+	m := &sysl.Module{}
+	if err := proto.Unmarshal(b, m); err != nil {
+		return nil, false
+	}
+	// Entries are evicted by the time of the manifest, so it's when they were last used.
+	now := time.Now()
+	_ = c.fs.Chtimes(filepath.Join(c.dir, key+".json"), now, now)
+	return m, true
+}
+
+// Put caches m, parsed from filename, along with the hashes of the local files and the versions of the imports
+// it was parsed from: the files read (see Recorder) and the files its definitions are in.
+func (c *Cache) Put(filename string, m *sysl.Module, read map[string]string) error {
+	key, err := c.key(filename)
+	if err != nil {
+		return err
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, filename)
+	}
+	if err := c.fs.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	if err := afero.WriteFile(c.fs, filepath.Join(c.dir, key+".pb"), b, 0644); err != nil {
+		return err
+	}
+	cached := manifest{Input: filename}
+	files := SourceFiles(m)
+	for file, version := range read {
+		files[file] = version
+	}
+	files[filename] = ""
+	files[ModulesFile] = ""
+	for _, file := range sortedKeys(files) {
+		dep := dependency{File: file, Version: files[file]}
+		if dep.Version == "" {
+			dep.Hash = c.hash(file)
+		}
+		cached.Dependencies = append(cached.Dependencies, dep)
+	}
+	if b, err = json.MarshalIndent(cached, "", "  "); err != nil {
+		return err
+	}
+	// The manifest is written last, so a module is never read without it.
+	if err := afero.WriteFile(c.fs, filepath.Join(c.dir, key+".json"), b, 0644); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes the least recently used entries beyond MaxEntries, such as the modules of files that were
+// parsed once or of older versions of the parser.
+func (c *Cache) evict() error {
+	infos, err := afero.ReadDir(c.fs, c.dir)
+	if err != nil {
+		return err
+	}
+	var manifests []os.FileInfo
+	for _, info := range infos {
+		if filepath.Ext(info.Name()) == ".json" {
+			manifests = append(manifests, info)
+		}
+	}
+	if len(manifests) <= MaxEntries {
+		return nil
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].ModTime().After(manifests[j].ModTime()) })
+	for _, info := range manifests[MaxEntries:] {
+		key := strings.TrimSuffix(info.Name(), ".json")
+		// The manifest is removed first, so a module is never read without it.
+		for _, name := range []string{key + ".json", key + ".pb"} {
+			if err := c.fs.Remove(filepath.Join(c.dir, name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// key returns the name of the cache entry of filename, which depends on the version of the parser too.
+func (c *Cache) key(filename string) (string, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, s := range []string{formatVersion, parserVersion(), abs} {
+		h.Write([]byte(s + "\x00"))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hash returns the hash of the contents of a file, or "" if it can't be read.
+func (c *Cache) hash(file string) string {
+	b, err := afero.ReadFile(c.fs, file)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// parserVersion returns the version of the sysl module the binary was built with, or "" if unknown.
+func parserVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/anz-bank/sysl" {
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+// SourceFiles returns the files the apps, endpoints, types and views of m are defined in, and the versions the
+// imported ones were retrieved at ("" for local files).
+func SourceFiles(m *sysl.Module) map[string]string {
+	files := make(map[string]string)
+	add := func(ctx *sysl.SourceContext) {
+		if ctx.GetFile() != "" {
+			files[ctx.GetFile()] = ctx.GetVersion()
+		}
+	}
+	for _, app := range m.GetApps() {
+		add(app.GetSourceContext())
+		for _, endpoint := range app.GetEndpoints() {
+			add(endpoint.GetSourceContext())
+		}
+		for _, t := range app.GetTypes() {
+			add(t.GetSourceContext())
+		}
+		for _, view := range app.GetViews() {
+			add(view.GetSourceContext())
+		}
+	}
+	return files
+}
+
+// Recorder is a retriever that records the files it retrieves, which are all the files a module is parsed from
+// (including imports that define nothing), to pass to Put.
+type Recorder struct {
+	retriever gop.Retriever
+	mu        sync.Mutex
+	files     map[string]string
+}
+
+// NewRecorder returns a Recorder that retrieves files with retriever.
+func NewRecorder(retriever gop.Retriever) *Recorder {
+	return &Recorder{retriever: retriever, files: make(map[string]string)}
+}
+
+// Retrieve retrieves resource, and records it if it's found.
+func (r *Recorder) Retrieve(resource string) ([]byte, bool, error) {
+	content, cached, err := r.retriever.Retrieve(resource)
+	if err != nil {
+		return content, cached, err
+	}
+	file, version := resource, ""
+	if i := strings.LastIndex(resource, "@"); i >= 0 {
+		file, version = resource[:i], resource[i+1:]
+	}
+	r.mu.Lock()
+	r.files[file] = version
+	r.mu.Unlock()
+	return content, cached, nil
+}
+
+// Files returns the files retrieved so far, and the versions the imported ones were retrieved at ("" for local
+// files).
+func (r *Recorder) Files() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	files := make(map[string]string, len(r.files))
+	for file, version := range r.files {
+		files[file] = version
+	}
+	return files
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
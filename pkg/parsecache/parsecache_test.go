@@ -0,0 +1,139 @@
+package parsecache
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anz-bank/sysl/pkg/sysl"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func testModule() *sysl.Module {
+	return &sysl.Module{Apps: map[string]*sysl.Application{
+		"A": {
+			Name:          &sysl.AppName{Part: []string{"A"}},
+			SourceContext: &sysl.SourceContext{File: "specs/a.sysl"},
+			Types: map[string]*sysl.Type{
+				"T": {SourceContext: &sysl.SourceContext{File: "specs/types.sysl"}},
+			},
+		},
+		"Remote": {
+			Name:          &sysl.AppName{Part: []string{"Remote"}},
+			SourceContext: &sysl.SourceContext{File: "github.com/org/repo/remote.sysl", Version: "v1.0.0"},
+		},
+	}}
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	for file, contents := range map[string]string{
+		"specs/a.sysl":     "import types\nA: ...\n",
+		"specs/types.sysl": "A:\n    !type T: ...\n",
+		"specs/other.sysl": "Other: ...\n",
+	} {
+		require.NoError(t, afero.WriteFile(fs, file, []byte(contents), 0644))
+	}
+	c := New(fs, "cache")
+	_, ok := c.Get("specs/a.sysl")
+	assert.False(t, ok)
+
+	m := testModule()
+	require.NoError(t, c.Put("specs/a.sysl", m, nil))
+	cached, ok := c.Get("specs/a.sysl")
+	require.True(t, ok)
+	assert.True(t, proto.Equal(m, cached))
+
+	require.NoError(t, afero.WriteFile(fs, "specs/other.sysl", []byte("Other: ...\nMore: ...\n"), 0644))
+	_, ok = c.Get("specs/a.sysl")
+	assert.True(t, ok, "files the module wasn't parsed from don't invalidate it")
+
+	require.NoError(t, afero.WriteFile(fs, "specs/types.sysl", []byte("A:\n    !type U: ...\n"), 0644))
+	_, ok = c.Get("specs/a.sysl")
+	assert.False(t, ok, "imported files invalidate it")
+
+	require.NoError(t, c.Put("specs/a.sysl", m, nil))
+	require.NoError(t, afero.WriteFile(fs, ModulesFile, []byte("modules: []\n"), 0644))
+	_, ok = c.Get("specs/a.sysl")
+	assert.False(t, ok, "the versions of imports invalidate it")
+}
+
+func TestSourceFiles(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, map[string]string{
+		"specs/a.sysl":                    "",
+		"specs/types.sysl":                "",
+		"github.com/org/repo/remote.sysl": "v1.0.0",
+	}, SourceFiles(testModule()))
+}
+
+// fsRetriever retrieves files from an afero.Fs.
+type fsRetriever struct{ fs afero.Fs }
+
+func (r fsRetriever) Retrieve(resource string) ([]byte, bool, error) {
+	b, err := afero.ReadFile(r.fs, resource)
+	return b, false, err
+}
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	for file, contents := range map[string]string{
+		"specs/a.sysl":      "import empty\nA: ...\n",
+		"specs/empty.sysl":  "# Nothing yet\n",
+		"specs/remote.sysl": "Remote: ...\n",
+	} {
+		require.NoError(t, afero.WriteFile(fs, file, []byte(contents), 0644))
+	}
+	r := NewRecorder(fsRetriever{fs})
+	for _, resource := range []string{"specs/a.sysl", "specs/empty.sysl", "specs/missing.sysl"} {
+		_, _, _ = r.Retrieve(resource)
+	}
+	read := r.Files()
+	assert.Equal(t, map[string]string{"specs/a.sysl": "", "specs/empty.sysl": ""}, read)
+
+	c := New(fs, "cache")
+	require.NoError(t, c.Put("specs/a.sysl", testModule(), read))
+	_, ok := c.Get("specs/a.sysl")
+	require.True(t, ok)
+	require.NoError(t, afero.WriteFile(fs, "specs/empty.sysl", []byte("Empty: ...\n"), 0644))
+	_, ok = c.Get("specs/a.sysl")
+	assert.False(t, ok, "imports that define nothing invalidate it")
+}
+
+func TestEvict(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	c := New(fs, "cache")
+	m := testModule()
+	for i := 0; i <= MaxEntries; i++ {
+		file := fmt.Sprintf("specs/%d.sysl", i)
+		require.NoError(t, c.Put(file, m, nil))
+		key, err := c.key(file)
+		require.NoError(t, err)
+		used := time.Now().Add(time.Duration(i-MaxEntries) * time.Minute)
+		require.NoError(t, fs.Chtimes(filepath.Join("cache", key+".json"), used, used))
+	}
+	_, ok := c.Get("specs/1.sysl")
+	require.True(t, ok, "using an entry keeps it")
+	require.NoError(t, c.Put("specs/new.sysl", m, nil))
+
+	_, ok = c.Get("specs/0.sysl")
+	assert.False(t, ok, "the least recently used entry is removed")
+	_, ok = c.Get("specs/1.sysl")
+	assert.True(t, ok)
+	_, ok = c.Get("specs/2.sysl")
+	assert.False(t, ok)
+	infos, err := afero.ReadDir(fs, "cache")
+	require.NoError(t, err)
+	assert.Len(t, infos, 2*MaxEntries)
+}
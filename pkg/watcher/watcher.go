@@ -1,40 +1,228 @@
+// Package watcher polls sysl files for changes and reports them in batches, so that editors saving several
+// files (or temporary files) at once cause a single regeneration.
 package watcher
 
 import (
+	"context"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/radovskyb/watcher"
 )
 
-func WatchFile(action func(i interface{}), files ...string) {
-	w := watcher.New()
-	// Only notify rename and move events.
-	w.FilterOps(watcher.Rename, watcher.Move, watcher.Write)
+const (
+	DefaultInterval = 100 * time.Millisecond // How often files are polled
+	DefaultDebounce = 300 * time.Millisecond // How long to wait for more changes before reporting them
+)
+
+// DefaultIgnore are the glob patterns of the temporary and backup files of common editors, and of hidden
+// files and directories.
+var DefaultIgnore = []string{".*", "*~", "#*#", "*.swp", "*.swx", "*.tmp", "*.bak", "4913"}
+
+// Options configure a Watcher.
+type Options struct {
+	Interval   time.Duration // How often files are polled; DefaultInterval if zero
+	Debounce   time.Duration // Changes closer together than this are reported together; DefaultDebounce if zero
+	Ignore     []string      // Glob patterns of the names (or paths, relative to a watched directory) to ignore
+	Extensions []string      // Extensions (".sysl") of the files in watched directories to report; all if empty
+	OnError    func(error)   // Called with the errors of watching; they're logged if nil
+}
+
+// Watcher watches directories (recursively) and files for changes.
+type Watcher struct {
+	opts    Options
+	w       *watcher.Watcher
+	mu      sync.Mutex
+	roots   []string // The absolute paths of the watched directories
+	watched map[string]bool
+}
+
+// New returns a Watcher that doesn't watch anything yet.
+func New(opts Options) *Watcher {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	w := &Watcher{opts: opts, w: watcher.New(), watched: make(map[string]bool)}
+	w.w.FilterOps(watcher.Create, watcher.Rename, watcher.Move, watcher.Write)
+	w.w.AddFilterHook(func(info os.FileInfo, fullPath string) error {
+		if w.Ignored(fullPath) || !info.IsDir() && !w.Reported(fullPath) {
+			return watcher.ErrSkip
+		}
+		return nil
+	})
+	return w
+}
+
+// Add watches paths: directories recursively and files on their own, such as imported files outside the
+// watched directories. Paths that are already watched are skipped, so Add can be called after every build.
+func (w *Watcher) Add(paths ...string) error {
+	for _, name := range paths {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return err
+		}
+		w.mu.Lock()
+		watched := w.watched[abs]
+		w.watched[abs] = true
+		w.mu.Unlock()
+		if watched {
+			continue
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			if err := w.w.Add(abs); err != nil {
+				return err
+			}
+			continue
+		}
+		w.mu.Lock()
+		w.roots = append(w.roots, abs)
+		w.mu.Unlock()
+		if err := w.w.AddRecursive(abs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ignored returns whether changes to a file are ignored: its name, one of its directories inside a watched
+// directory, or its path relative to that directory match an ignore pattern.
+func (w *Watcher) Ignored(fullPath string) bool {
+	candidates := []string{filepath.Base(fullPath)}
+	w.mu.Lock()
+	roots := w.roots
+	w.mu.Unlock()
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, fullPath)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		candidates = append(append(candidates, rel), strings.Split(rel, "/")...)
+	}
+	for _, pattern := range w.opts.Ignore {
+		for _, candidate := range candidates {
+			if matched, _ := filepath.Match(pattern, candidate); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Reported returns whether changes to a file that isn't ignored are reported: it has one of the extensions, or it
+// was added on its own.
+func (w *Watcher) Reported(fullPath string) bool {
+	if len(w.opts.Extensions) == 0 {
+		return true
+	}
+	ext := filepath.Ext(fullPath)
+	for _, e := range w.opts.Extensions {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.watched[fullPath]
+}
+
+// Run calls action with the changes to the watched files until ctx is done. Changes are reported together
+// once no more have been seen for the debounce window, and action is called once with no changes when
+// watching starts, for an initial build.
+func (w *Watcher) Run(ctx context.Context, action func(events []watcher.Event)) error {
+	started, failed := make(chan struct{}), make(chan struct{})
+	go func() {
+		w.w.Wait() // Returns once Start is called, unless it fails as the interval is too short
+		close(started)
+	}()
 	go func() {
+		select {
+		case <-started:
+		case <-failed:
+			return
+		case <-ctx.Done():
+			select { // Stop watching once it's started
+			case <-started:
+				w.w.Close()
+			case <-failed:
+			}
+			return
+		}
+		action(nil)
+		var pending []watcher.Event
+		timer := time.NewTimer(w.opts.Debounce)
+		timer.Stop()
 		for {
 			select {
-			case event := <-w.Event:
-				action(event)
-			case err := <-w.Error:
-				log.Fatalln(err)
-			case <-w.Closed:
+			case event := <-w.w.Event:
+				if event.IsDir() {
+					continue
+				}
+				pending = append(pending, event)
+				timer.Reset(w.opts.Debounce)
+			case <-timer.C:
+				if len(pending) > 0 {
+					action(pending)
+					pending = nil
+				}
+			case err := <-w.w.Error:
+				w.error(err)
+			case <-ctx.Done():
+				timer.Stop()
+				w.w.Close()
+				return
+			case <-w.w.Closed:
 				return
 			}
 		}
 	}()
-	// Watch test_folder recursively for changes.
-	for _, file := range files {
-		if err := w.AddRecursive(file); err != nil {
-			log.Fatalln(err)
-		}
+	err := w.w.Start(w.opts.Interval)
+	if err != nil {
+		close(failed)
 	}
-	go func() {
-		w.Wait()
-		w.TriggerEvent(watcher.Write, nil)
-	}()
-	// Start the watching process - it'll check for changes every 100ms.
-	if err := w.Start(time.Millisecond * 100); err != nil {
-		log.Fatalln(err)
+	return err
+}
+
+// error reports an error of watching to OnError.
+func (w *Watcher) error(err error) {
+	if w.opts.OnError != nil {
+		w.opts.OnError(err)
+		return
+	}
+	log.Println("watcher:", err)
+}
+
+// WatchFile calls action with each change to files and the directories of files, and once when watching
+// starts, until the process exits. Directories are watched recursively and files on their own, as before
+// Add, but changes to directories themselves are no longer reported, created files are, and errors are
+// logged instead of exiting.
+//
+// Deprecated: use New and Run, which batch changes, ignore temporary files and can be stopped.
+func WatchFile(action func(i interface{}), files ...string) {
+	w := New(Options{Debounce: time.Nanosecond})
+	if err := w.Add(files...); err != nil {
+		w.error(err)
+	}
+	err := w.Run(context.Background(), func(events []watcher.Event) {
+		if events == nil {
+			action(watcher.Event{Op: watcher.Write})
+		}
+		for _, event := range events {
+			action(event)
+		}
+	})
+	if err != nil {
+		w.error(err)
 	}
 }
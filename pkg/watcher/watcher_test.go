@@ -0,0 +1,149 @@
+package watcher
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/radovskyb/watcher"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnored(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "watcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "generated"), 0755))
+
+	w := New(Options{Ignore: append(append([]string{}, DefaultIgnore...), "generated/*")})
+	require.NoError(t, w.Add(dir))
+
+	assert.False(t, w.Ignored(filepath.Join(dir, "api.sysl")))
+	assert.True(t, w.Ignored(filepath.Join(dir, ".api.sysl.swp")))
+	assert.True(t, w.Ignored(filepath.Join(dir, "api.sysl~")))
+	assert.True(t, w.Ignored(filepath.Join(dir, "#api.sysl#")))
+	assert.True(t, w.Ignored(filepath.Join(dir, ".git", "HEAD")))
+	assert.True(t, w.Ignored(filepath.Join(dir, "generated", "api.sysl")))
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "watcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := New(Options{Interval: 10 * time.Millisecond, Debounce: 100 * time.Millisecond, Ignore: DefaultIgnore})
+	require.NoError(t, w.Add(dir))
+
+	batches := make(chan []watcher.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx, func(events []watcher.Event) { batches <- events }) }()
+
+	select {
+	case events := <-batches:
+		assert.Nil(t, events)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no initial call")
+	}
+
+	for _, name := range []string{"a.sysl", "b.sysl", "a.sysl.swp"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("App:\n"), 0644))
+	}
+	select {
+	case events := <-batches:
+		var names []string
+		for _, event := range events {
+			names = append(names, filepath.Base(event.Path))
+		}
+		assert.ElementsMatch(t, []string{"a.sysl", "b.sysl"}, names)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no changes reported")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after the context was done")
+	}
+	assert.Empty(t, batches)
+}
+
+func TestRunDone(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "watcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w := New(Options{Interval: 10 * time.Millisecond})
+	require.NoError(t, w.Add(dir))
+
+	// Run returns when the context is done before watching has started.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan error)
+	go func() { done <- w.Run(ctx, func(events []watcher.Event) {}) }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after the context was done")
+	}
+}
+
+func TestRunExtensions(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "watcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	imported, err := ioutil.TempDir("", "watcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(imported)
+	added := filepath.Join(imported, "spec.txt")
+	require.NoError(t, ioutil.WriteFile(added, []byte("App:\n"), 0644))
+
+	w := New(Options{Interval: 10 * time.Millisecond, Debounce: 100 * time.Millisecond, Extensions: []string{".sysl"}})
+	require.NoError(t, w.Add(dir, added))
+	assert.True(t, w.Reported(filepath.Join(dir, "api.SYSL")))
+	assert.False(t, w.Reported(filepath.Join(dir, "README.md")))
+	assert.True(t, w.Reported(added))
+
+	batches := make(chan []watcher.Event, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = w.Run(ctx, func(events []watcher.Event) { batches <- events }) }()
+	<-batches
+
+	for _, name := range []string{"README.md", "api.json"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("docs\n"), 0644))
+	}
+	select {
+	case events := <-batches:
+		t.Fatalf("changes to files without the extensions reported: %v", events)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "api.sysl"), []byte("App:\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(added, []byte("App:\n    ...\n"), 0644))
+	select {
+	case events := <-batches:
+		var paths []string
+		for _, event := range events {
+			paths = append(paths, event.Path)
+		}
+		assert.ElementsMatch(t, []string{filepath.Join(dir, "api.sysl"), added}, paths)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no changes reported")
+	}
+}